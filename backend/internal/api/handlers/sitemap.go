@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"website-crawler/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// sitemapTimeout bounds reading and checking a site's sitemaps during an import
+const sitemapTimeout = 2 * time.Minute
+
+// ImportSitemap starts adding the URLs listed in a site's sitemaps to the
+// user's list. Reading and checking the sitemaps runs in the background; the
+// returned import reports its progress and the problems found.
+func (h *URLHandler) ImportSitemap(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	var req models.SitemapImportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	include, err := compilePatterns(req.Include)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid include pattern: " + err.Error()})
+		return
+	}
+	exclude, err := compilePatterns(req.Exclude)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid exclude pattern: " + err.Error()})
+		return
+	}
+	keep := func(loc string) bool {
+		for _, pattern := range exclude {
+			if pattern.MatchString(loc) {
+				return false
+			}
+		}
+		if len(include) == 0 {
+			return true
+		}
+		for _, pattern := range include {
+			if pattern.MatchString(loc) {
+				return true
+			}
+		}
+		return false
+	}
+
+	job := models.SitemapImport{
+		UserID:    user.ID,
+		URL:       req.URL,
+		Status:    "running",
+		DryRun:    req.DryRun,
+		StartedAt: time.Now(),
+	}
+	if err := h.db.Create(&job).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create sitemap import"})
+		return
+	}
+
+	go h.importSitemap(job, keep, req.Crawl)
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetSitemapImport returns the progress and outcome of a sitemap import
+func (h *URLHandler) GetSitemapImport(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	id, err := strconv.ParseUint(c.Param("importId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid import ID"})
+		return
+	}
+
+	var job models.SitemapImport
+	if err := h.db.Where("id = ? AND user_id = ?", id, user.ID).First(&job).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Sitemap import not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sitemap import"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// importSitemap reads a site's sitemaps and adds the URLs they list, unless
+// the import is a dry run, then records the outcome on the import
+func (h *URLHandler) importSitemap(job models.SitemapImport, keep func(loc string) bool, crawl bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), sitemapTimeout)
+	defer cancel()
+
+	var imported []models.URL
+	report, err := h.crawler.ReadSitemaps(ctx, job.URL, keep)
+	if err == nil && !job.DryRun {
+		imported = h.addSitemapURLs(&job, &report)
+	}
+
+	finishedAt := time.Now()
+	job.FinishedAt = &finishedAt
+	job.Status = "completed"
+	if err != nil {
+		job.Status = "failed"
+		job.Error = err.Error()
+	} else if reportJSON, err := json.Marshal(report); err == nil {
+		job.Report = string(reportJSON)
+	}
+
+	if err := h.db.Save(&job).Error; err != nil {
+		log.Printf("Failed to store sitemap import %d: %v", job.ID, err)
+	}
+	if h.ws != nil {
+		h.ws.BroadcastSitemapImport(job)
+	}
+
+	// Imported URLs are analyzed like newly added ones; the crawler spaces out
+	// requests to the site by its Crawl-delay
+	if crawl {
+		for _, url := range imported {
+			go h.crawlURL(url)
+		}
+	}
+}
+
+// addSitemapURLs adds the URLs of a report that are not in the user's list
+// yet, counting them on the import, and returns the URLs added. A URL can only
+// be monitored once across all users, so one another user already has is
+// reported in the report's errors rather than added.
+func (h *URLHandler) addSitemapURLs(job *models.SitemapImport, report *models.SitemapReport) []models.URL {
+	locs := make([]string, 0, len(report.URLs))
+	for _, entry := range report.URLs {
+		locs = append(locs, entry.Loc)
+	}
+	owners := map[string]uint{}
+	for start := 0; start < len(locs); start += 1000 {
+		var found []models.URL
+		h.db.Select("url", "user_id").Where("url IN ?", locs[start:min(start+1000, len(locs))]).Find(&found)
+		for _, url := range found {
+			owners[url.URL] = url.UserID
+		}
+	}
+
+	fail := func(entry models.SitemapURL, code, message string) {
+		job.Failed++
+		report.Errors = append(report.Errors, models.SitemapError{Sitemap: entry.Sitemap, URL: entry.Loc, Code: code, Message: message})
+	}
+
+	var imported []models.URL
+	for _, entry := range report.URLs {
+		if owner, exists := owners[entry.Loc]; exists {
+			if owner == job.UserID {
+				job.Existing++
+			} else {
+				fail(entry, models.SitemapErrorExists, "URL already exists")
+			}
+			continue
+		}
+
+		url := models.URL{
+			URL:       entry.Loc,
+			Status:    "pending",
+			CrawlMode: "page",
+			UserID:    job.UserID,
+		}
+		if err := h.db.Create(&url).Error; err != nil {
+			// The URL may have been added since the lookup above
+			var count int64
+			if h.db.Model(&models.URL{}).Where("url = ?", entry.Loc).Count(&count); count > 0 {
+				fail(entry, models.SitemapErrorExists, "URL already exists")
+			} else {
+				fail(entry, models.SitemapErrorImportFailed, "Failed to add URL")
+			}
+			continue
+		}
+		imported = append(imported, url)
+	}
+	job.Imported = len(imported)
+	return imported
+}
+
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		regex, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, regex)
+	}
+	return compiled, nil
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"website-crawler/internal/crawler"
+	"website-crawler/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// TechnologyHandler handles user-defined technology fingerprinting rules
+type TechnologyHandler struct {
+	db *gorm.DB
+}
+
+// NewTechnologyHandler creates a new technology handler
+func NewTechnologyHandler(db *gorm.DB) *TechnologyHandler {
+	return &TechnologyHandler{db: db}
+}
+
+// ListTechnologies returns the current user's technology rules
+func (h *TechnologyHandler) ListTechnologies(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	var technologies []models.CustomTechnology
+	if err := h.db.Where("user_id = ?", user.ID).Order("name").Find(&technologies).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch technologies"})
+		return
+	}
+
+	c.JSON(http.StatusOK, technologies)
+}
+
+// AddTechnology adds a technology rule that is checked when the user's URLs are analyzed
+func (h *TechnologyHandler) AddTechnology(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	var rule crawler.TechnologyRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := crawler.ValidateTechnologyRule(rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ruleJSON, err := json.Marshal(rule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	technology := models.CustomTechnology{
+		UserID: user.ID,
+		Name:   rule.Name,
+		Rule:   string(ruleJSON),
+	}
+	if err := h.db.Create(&technology).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create technology"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, technology)
+}
+
+// DeleteTechnology deletes one of the user's technology rules
+func (h *TechnologyHandler) DeleteTechnology(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid technology ID"})
+		return
+	}
+
+	result := h.db.Where("id = ? AND user_id = ?", id, user.ID).Delete(&models.CustomTechnology{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete technology"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Technology not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Technology deleted successfully"})
+}
+
+// userTechnologies returns the technology rules a user has defined
+func userTechnologies(db *gorm.DB, userID uint) ([]crawler.TechnologyRule, error) {
+	var technologies []models.CustomTechnology
+	if err := db.Where("user_id = ?", userID).Find(&technologies).Error; err != nil {
+		return nil, fmt.Errorf("failed to load custom technologies: %w", err)
+	}
+
+	rules := make([]crawler.TechnologyRule, 0, len(technologies))
+	for _, technology := range technologies {
+		var rule crawler.TechnologyRule
+		if err := json.Unmarshal([]byte(technology.Rule), &rule); err == nil {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
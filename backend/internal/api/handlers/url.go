@@ -2,8 +2,13 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"log"
 	"net/http"
+	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"website-crawler/internal/crawler"
@@ -13,6 +18,20 @@ import (
 	"gorm.io/gorm"
 )
 
+// latestCertExpiry selects the certificate expiry from a URL's most recent analysis
+const latestCertExpiry = "(SELECT a.cert_expires_at FROM analyses a WHERE a.url_id = urls.id ORDER BY a.id DESC LIMIT 1)"
+
+// latestTechnologies selects the technologies detected by a URL's most recent analysis
+const latestTechnologies = "SELECT 1 FROM detected_technologies t WHERE t.analysis_id = (SELECT a.id FROM analyses a WHERE a.url_id = urls.id ORDER BY a.id DESC LIMIT 1)"
+
+// likeEscaper escapes the LIKE wildcards, and the escape character itself,
+// so user input only matches literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func escapeLike(value string) string {
+	return likeEscaper.Replace(value)
+}
+
 // URLHandler handles URL-related requests
 type URLHandler struct {
 	db      *gorm.DB
@@ -22,9 +41,15 @@ type URLHandler struct {
 
 // NewURLHandler creates a new URL handler
 func NewURLHandler(db *gorm.DB, ws *WebSocketHandler) *URLHandler {
+	cfg := crawler.DefaultConfig()
+	cfg.TechnologyRulesFile = os.Getenv("TECHNOLOGY_RULES_FILE")
+	if maxBodySize, err := strconv.ParseInt(os.Getenv("MAX_BODY_SIZE"), 10, 64); err == nil && maxBodySize > 0 {
+		cfg.MaxBodySize = maxBodySize
+	}
+
 	return &URLHandler{
 		db:      db,
-		crawler: crawler.NewCrawlerService(),
+		crawler: crawler.NewCrawlerServiceWithConfig(cfg),
 		ws:      ws,
 	}
 }
@@ -38,6 +63,9 @@ func (h *URLHandler) ListURLs(c *gin.Context) {
 	search := c.DefaultQuery("search", "")
 	sortBy := c.DefaultQuery("sort_by", "created_at")
 	sortOrder := c.DefaultQuery("sort_order", "desc")
+	certExpiresWithin := c.DefaultQuery("cert_expires_within", "")
+	technology := c.DefaultQuery("technology", "")
+	technologyVersion := c.DefaultQuery("technology_version", "")
 
 	offset := (page - 1) * pageSize
 
@@ -50,12 +78,31 @@ func (h *URLHandler) ListURLs(c *gin.Context) {
 		query = query.Where("url LIKE ?", "%"+search+"%")
 	}
 
+	// Only URLs whose certificate expires within the given number of days
+	if days, err := strconv.Atoi(certExpiresWithin); err == nil {
+		query = query.Where(latestCertExpiry+" <= ?", time.Now().AddDate(0, 0, days))
+	}
+
+	// Only URLs running a technology, optionally at a version such as 5 or 5.8
+	if technology != "" {
+		if technologyVersion != "" {
+			query = query.Where("EXISTS ("+latestTechnologies+" AND t.name = ? AND (t.version = ? OR t.version LIKE ?))",
+				technology, technologyVersion, escapeLike(technologyVersion)+".%")
+		} else {
+			query = query.Where("EXISTS ("+latestTechnologies+" AND t.name = ?)", technology)
+		}
+	}
+
 	// Count total
 	query.Model(&models.URL{}).Count(&total)
 
 	// Get URLs with pagination and sorting
 	orderClause := sortBy + " " + sortOrder
-	if err := query.Preload("Analysis").Order(orderClause).Offset(offset).Limit(pageSize).Find(&urls).Error; err != nil {
+	if sortBy == "cert_days_to_expiry" {
+		// URLs without a certificate sort last in either direction
+		orderClause = latestCertExpiry + " IS NULL, " + latestCertExpiry + " " + sortOrder
+	}
+	if err := query.Preload("Analysis").Preload("Analysis.Technologies").Order(orderClause).Offset(offset).Limit(pageSize).Find(&urls).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch URLs"})
 		return
 	}
@@ -121,9 +168,23 @@ func (h *URLHandler) AddURL(c *gin.Context) {
 	}
 
 	url := models.URL{
-		URL:    req.URL,
-		Status: "pending",
-		UserID: user.ID,
+		URL:          req.URL,
+		Status:       "pending",
+		CrawlMode:    "page",
+		IgnoreRobots: req.IgnoreRobots,
+		UserID:       user.ID,
+	}
+
+	if req.CrawlMode == "site" {
+		url.CrawlMode = "site"
+		url.MaxDepth = crawler.DefaultMaxDepth
+		url.MaxPages = req.MaxPages
+		if req.MaxDepth != nil {
+			url.MaxDepth = *req.MaxDepth
+		}
+		if url.MaxPages == 0 {
+			url.MaxPages = crawler.DefaultMaxPages
+		}
 	}
 
 	if err := h.db.Create(&url).Error; err != nil {
@@ -132,7 +193,7 @@ func (h *URLHandler) AddURL(c *gin.Context) {
 	}
 
 	// Start crawling in background
-	go h.crawlURL(url.ID, req.URL)
+	go h.crawlURL(url)
 
 	c.JSON(http.StatusCreated, url)
 }
@@ -148,7 +209,36 @@ func (h *URLHandler) GetURL(c *gin.Context) {
 	}
 
 	var url models.URL
-	if err := h.db.Where("id = ? AND user_id = ?", id, user.ID).Preload("Analysis").First(&url).Error; err != nil {
+	if err := h.db.Where("id = ? AND user_id = ?", id, user.ID).Preload("Analysis").Preload("Analysis.Technologies").Preload("CrawlJob").First(&url).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "URL not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch URL"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, url)
+}
+
+// UpdateURL changes a URL's crawl settings, which apply from its next analysis
+func (h *URLHandler) UpdateURL(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid URL ID"})
+		return
+	}
+
+	var req models.UpdateURLRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var url models.URL
+	if err := h.db.Where("id = ? AND user_id = ?", id, user.ID).First(&url).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "URL not found"})
 		} else {
@@ -157,6 +247,14 @@ func (h *URLHandler) GetURL(c *gin.Context) {
 		return
 	}
 
+	if req.IgnoreRobots != nil {
+		url.IgnoreRobots = *req.IgnoreRobots
+	}
+	if err := h.db.Model(&url).Update("ignore_robots", url.IgnoreRobots).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update URL"})
+		return
+	}
+
 	c.JSON(http.StatusOK, url)
 }
 
@@ -184,7 +282,7 @@ func (h *URLHandler) RerunAnalysis(c *gin.Context) {
 	h.db.Model(&url).Update("status", "running")
 
 	// Start crawling in background
-	go h.crawlURL(url.ID, url.URL)
+	go h.crawlURL(url)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Analysis started"})
 }
@@ -254,7 +352,7 @@ func (h *URLHandler) BulkRerun(c *gin.Context) {
 	// Update status and start crawling for each URL
 	for _, url := range urls {
 		h.db.Model(&url).Update("status", "running")
-		go h.crawlURL(url.ID, url.URL)
+		go h.crawlURL(url)
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -263,9 +361,180 @@ func (h *URLHandler) BulkRerun(c *gin.Context) {
 	})
 }
 
+// ListBrokenLinks returns the broken links of a URL's latest analysis,
+// optionally filtered by failure category and resource type, along with
+// per-category counts
+func (h *URLHandler) ListBrokenLinks(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid URL ID"})
+		return
+	}
+
+	category := c.DefaultQuery("category", "")
+	resourceType := c.DefaultQuery("resource_type", "")
+
+	var url models.URL
+	if err := h.db.Where("id = ? AND user_id = ?", id, user.ID).First(&url).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "URL not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch URL"})
+		}
+		return
+	}
+
+	var analysis models.Analysis
+	if err := h.db.Where("url_id = ?", url.ID).Order("id desc").First(&analysis).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No analysis found for this URL"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch analysis"})
+		}
+		return
+	}
+
+	var brokenLinks []models.BrokenLink
+	if analysis.BrokenLinks != "" {
+		if err := json.Unmarshal([]byte(analysis.BrokenLinks), &brokenLinks); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read broken links"})
+			return
+		}
+	}
+
+	response := models.BrokenLinkListResponse{
+		BrokenLinks: []models.BrokenLink{},
+		Counts:      make(map[string]int),
+	}
+	for _, link := range brokenLinks {
+		if resourceType != "" && link.ResourceType != resourceType {
+			continue
+		}
+		response.Counts[link.Category]++
+		if category == "" || link.Category == category {
+			response.BrokenLinks = append(response.BrokenLinks, link)
+		}
+	}
+	response.Total = len(response.BrokenLinks)
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ListCrawlPages returns a paginated list of pages discovered by the latest site crawl of a URL
+func (h *URLHandler) ListCrawlPages(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid URL ID"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 20
+	}
+
+	job, ok := h.findCrawlJob(c, uint(id), user.ID)
+	if !ok {
+		return
+	}
+
+	var pages []models.CrawlPage
+	var total int64
+
+	query := h.db.Model(&models.CrawlPage{}).Where("crawl_job_id = ?", job.ID)
+	query.Count(&total)
+
+	if err := query.Omit("analysis").Order("depth asc, id asc").Offset((page - 1) * pageSize).Limit(pageSize).Find(&pages).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pages"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.CrawlPageListResponse{
+		Pages:      pages,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: (int(total) + pageSize - 1) / pageSize,
+	})
+}
+
+// GetCrawlPage returns the full analysis of a single page from the latest site crawl of a URL
+func (h *URLHandler) GetCrawlPage(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid URL ID"})
+		return
+	}
+
+	pageID, err := strconv.ParseUint(c.Param("pageId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page ID"})
+		return
+	}
+
+	job, ok := h.findCrawlJob(c, uint(id), user.ID)
+	if !ok {
+		return
+	}
+
+	var page models.CrawlPage
+	if err := h.db.Where("id = ? AND crawl_job_id = ?", pageID, job.ID).First(&page).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Page not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch page"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// findCrawlJob loads the crawl job for a URL owned by the user, writing an
+// error response and returning false if it cannot be found
+func (h *URLHandler) findCrawlJob(c *gin.Context, urlID, userID uint) (models.CrawlJob, bool) {
+	var url models.URL
+	if err := h.db.Where("id = ? AND user_id = ?", urlID, userID).First(&url).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "URL not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch URL"})
+		}
+		return models.CrawlJob{}, false
+	}
+
+	var job models.CrawlJob
+	if err := h.db.Where("url_id = ?", url.ID).Order("id desc").First(&job).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No site crawl found for this URL"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch crawl job"})
+		}
+		return models.CrawlJob{}, false
+	}
+
+	return job, true
+}
+
 // crawlURL performs the actual crawling and analysis
-func (h *URLHandler) crawlURL(urlID uint, targetURL string) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+func (h *URLHandler) crawlURL(url models.URL) {
+	urlID := url.ID
+
+	timeout := 5 * time.Minute
+	if url.CrawlMode == "site" {
+		timeout = 30 * time.Minute
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
 	// Update status to running
@@ -280,7 +549,15 @@ func (h *URLHandler) crawlURL(urlID uint, targetURL string) {
 	}
 
 	// Perform crawling
-	analysis, err := h.crawler.CrawlWebsite(ctx, targetURL)
+	var analysis *models.Analysis
+	opts, err := h.pageOptions(url)
+	if err == nil {
+		if url.CrawlMode == "site" {
+			analysis, err = h.crawlSite(ctx, url, opts)
+		} else {
+			analysis, err = h.crawler.CrawlWebsiteWithOptions(ctx, url.URL, opts)
+		}
+	}
 	if err != nil {
 		h.db.Model(&models.URL{ID: urlID}).Update("status", "failed")
 
@@ -322,3 +599,124 @@ func (h *URLHandler) crawlURL(urlID uint, targetURL string) {
 		})
 	}
 }
+
+// pageOptions collects the per-URL crawler settings for a URL
+func (h *URLHandler) pageOptions(url models.URL) (crawler.PageOptions, error) {
+	technologies, err := userTechnologies(h.db, url.UserID)
+	if err != nil {
+		return crawler.PageOptions{}, err
+	}
+	return crawler.PageOptions{
+		Technologies: technologies,
+		IgnoreRobots: url.IgnoreRobots,
+	}, nil
+}
+
+// crawlSite runs a multi-page crawl for a URL, storing each page under a new
+// crawl job, and returns the analysis of the start page
+func (h *URLHandler) crawlSite(ctx context.Context, url models.URL, pageOpts crawler.PageOptions) (*models.Analysis, error) {
+	// Only the latest crawl job is kept per URL
+	var oldJobIDs []uint
+	h.db.Model(&models.CrawlJob{}).Where("url_id = ?", url.ID).Pluck("id", &oldJobIDs)
+	if len(oldJobIDs) > 0 {
+		h.db.Where("crawl_job_id IN ?", oldJobIDs).Delete(&models.CrawlPage{})
+		h.db.Where("id IN ?", oldJobIDs).Delete(&models.CrawlJob{})
+	}
+
+	job := models.CrawlJob{
+		URLID:     url.ID,
+		Status:    "running",
+		MaxDepth:  url.MaxDepth,
+		MaxPages:  url.MaxPages,
+		StartedAt: time.Now(),
+	}
+	if err := h.db.Create(&job).Error; err != nil {
+		return nil, err
+	}
+
+	opts := crawler.SiteCrawlOptions{
+		MaxDepth:    url.MaxDepth,
+		MaxPages:    url.MaxPages,
+		PageOptions: pageOpts,
+	}
+
+	// Pages that could not be stored leave the job partial rather than silently short
+	var pagesNotSaved int
+	var saveErr error
+
+	results, crawlErr := h.crawler.CrawlSite(ctx, url.URL, opts, func(result crawler.PageResult) {
+		page := models.CrawlPage{
+			CrawlJobID: job.ID,
+			URL:        result.URL,
+			Depth:      result.Depth,
+			Status:     "completed",
+		}
+
+		if result.Err != nil {
+			page.Status = "failed"
+			page.Error = result.Err.Error()
+			job.PagesFailed++
+		} else {
+			page.Title = result.Analysis.Title
+			page.InternalLinks = result.Analysis.InternalLinks
+			page.ExternalLinks = result.Analysis.ExternalLinks
+			page.InaccessibleLinks = result.Analysis.InaccessibleLinks
+			if analysisJSON, err := json.Marshal(result.Analysis); err == nil {
+				page.Analysis = string(analysisJSON)
+			}
+
+			job.TotalInternalLinks += result.Analysis.InternalLinks
+			job.TotalExternalLinks += result.Analysis.ExternalLinks
+			job.TotalInaccessibleLinks += result.Analysis.InaccessibleLinks
+			if result.Analysis.HasLoginForm {
+				job.PagesWithLoginForm++
+			}
+		}
+		job.PagesCrawled++
+
+		if err := h.db.Create(&page).Error; err != nil {
+			log.Printf("Failed to store page %s of crawl job %d: %v", page.URL, job.ID, err)
+			pagesNotSaved++
+			saveErr = err
+		}
+		h.db.Model(&job).Updates(map[string]interface{}{
+			"pages_crawled":            job.PagesCrawled,
+			"pages_failed":             job.PagesFailed,
+			"total_internal_links":     job.TotalInternalLinks,
+			"total_external_links":     job.TotalExternalLinks,
+			"total_inaccessible_links": job.TotalInaccessibleLinks,
+			"pages_with_login_form":    job.PagesWithLoginForm,
+		})
+	})
+
+	finishedAt := time.Now()
+	job.FinishedAt = &finishedAt
+	job.Status = "completed"
+
+	var startErr error
+	if len(results) == 0 {
+		startErr = crawlErr
+	} else if results[0].Err != nil {
+		startErr = results[0].Err
+	}
+	if startErr != nil {
+		job.Status = "failed"
+		job.Error = startErr.Error()
+	} else if saveErr != nil {
+		job.Status = "partial"
+		job.Error = fmt.Sprintf("%d of %d pages could not be saved: %v", pagesNotSaved, job.PagesCrawled, saveErr)
+	} else if crawlErr != nil {
+		job.Error = crawlErr.Error()
+	}
+
+	h.db.Model(&job).Updates(map[string]interface{}{
+		"status":      job.Status,
+		"error":       job.Error,
+		"finished_at": job.FinishedAt,
+	})
+
+	if startErr != nil {
+		return nil, startErr
+	}
+	return results[0].Analysis, nil
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"sync"
 
+	"website-crawler/internal/middleware"
 	"website-crawler/internal/models"
 
 	"github.com/gin-gonic/gin"
@@ -17,11 +18,25 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
+// wsClient is a WebSocket connection and the user it authenticated as, or 0
+// for an anonymous connection
+type wsClient struct {
+	conn   *websocket.Conn
+	userID uint
+}
+
+// wsMessage is a message for every client, or only for one user's clients
+// when userID is set
+type wsMessage struct {
+	message models.WebSocketMessage
+	userID  uint
+}
+
 // WebSocketHandler handles WebSocket connections
 type WebSocketHandler struct {
-	clients    map[*websocket.Conn]bool
-	broadcast  chan models.WebSocketMessage
-	register   chan *websocket.Conn
+	clients    map[*websocket.Conn]uint
+	broadcast  chan wsMessage
+	register   chan wsClient
 	unregister chan *websocket.Conn
 	mutex      sync.RWMutex
 }
@@ -29,9 +44,9 @@ type WebSocketHandler struct {
 // NewWebSocketHandler creates a new WebSocket handler
 func NewWebSocketHandler() *WebSocketHandler {
 	handler := &WebSocketHandler{
-		clients:    make(map[*websocket.Conn]bool),
-		broadcast:  make(chan models.WebSocketMessage),
-		register:   make(chan *websocket.Conn),
+		clients:    make(map[*websocket.Conn]uint),
+		broadcast:  make(chan wsMessage),
+		register:   make(chan wsClient),
 		unregister: make(chan *websocket.Conn),
 	}
 
@@ -39,14 +54,26 @@ func NewWebSocketHandler() *WebSocketHandler {
 	return handler
 }
 
-// HandleWebSocket handles WebSocket connections
+// HandleWebSocket handles WebSocket connections. Browsers cannot set headers
+// on a WebSocket, so a connection authenticates with a token query parameter
+// to receive the updates meant only for its user.
 func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
+	var userID uint
+	if token := c.Query("token"); token != "" {
+		id, err := middleware.ParseUserToken(token)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		userID = id
+	}
+
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
 		return
 	}
 
-	h.register <- conn
+	h.register <- wsClient{conn: conn, userID: userID}
 
 	// Handle incoming messages
 	go func() {
@@ -56,17 +83,17 @@ func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
 		}()
 
 		for {
-			_, message, err := conn.ReadMessage()
+			_, data, err := conn.ReadMessage()
 			if err != nil {
 				break
 			}
 
-			var wsMessage models.WebSocketMessage
-			if err := json.Unmarshal(message, &wsMessage); err != nil {
+			var message models.WebSocketMessage
+			if err := json.Unmarshal(data, &message); err != nil {
 				continue
 			}
 
-			h.broadcast <- wsMessage
+			h.broadcast <- wsMessage{message: message}
 		}
 	}()
 }
@@ -77,7 +104,7 @@ func (h *WebSocketHandler) run() {
 		select {
 		case client := <-h.register:
 			h.mutex.Lock()
-			h.clients[client] = true
+			h.clients[client.conn] = client.userID
 			h.mutex.Unlock()
 
 		case client := <-h.unregister:
@@ -87,8 +114,11 @@ func (h *WebSocketHandler) run() {
 
 		case message := <-h.broadcast:
 			h.mutex.RLock()
-			for client := range h.clients {
-				err := client.WriteJSON(message)
+			for client, userID := range h.clients {
+				if message.userID != 0 && message.userID != userID {
+					continue
+				}
+				err := client.WriteJSON(message.message)
 				if err != nil {
 					client.Close()
 					delete(h.clients, client)
@@ -106,7 +136,7 @@ func (h *WebSocketHandler) BroadcastStatus(status models.CrawlStatus) {
 		Payload: status,
 	}
 
-	h.broadcast <- message
+	h.broadcast <- wsMessage{message: message}
 }
 
 // BroadcastURLUpdate broadcasts URL updates to all connected clients
@@ -116,5 +146,22 @@ func (h *WebSocketHandler) BroadcastURLUpdate(url models.URL) {
 		Payload: url,
 	}
 
-	h.broadcast <- message
+	h.broadcast <- wsMessage{message: message}
+}
+
+// BroadcastSitemapImport sends the outcome of a sitemap import to the clients
+// of the user who started it. The report stays behind GetSitemapImport.
+func (h *WebSocketHandler) BroadcastSitemapImport(job models.SitemapImport) {
+	message := models.WebSocketMessage{
+		Type: "sitemap_import",
+		Payload: models.SitemapImportStatus{
+			ImportID: job.ID,
+			Status:   job.Status,
+			Imported: job.Imported,
+			Existing: job.Existing,
+			Failed:   job.Failed,
+		},
+	}
+
+	h.broadcast <- wsMessage{message: message, userID: job.UserID}
 }
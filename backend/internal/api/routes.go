@@ -14,6 +14,7 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB) {
 	authHandler := handlers.NewAuthHandler(db)
 	wsHandler := handlers.NewWebSocketHandler()
 	urlHandler := handlers.NewURLHandler(db, wsHandler)
+	technologyHandler := handlers.NewTechnologyHandler(db)
 
 	// Public routes
 	api := r.Group("/api")
@@ -39,10 +40,24 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB) {
 				urls.GET("", urlHandler.ListURLs)
 				urls.POST("", urlHandler.AddURL)
 				urls.GET("/:id", urlHandler.GetURL)
+				urls.PUT("/:id", urlHandler.UpdateURL)
+				urls.GET("/:id/broken-links", urlHandler.ListBrokenLinks)
+				urls.GET("/:id/pages", urlHandler.ListCrawlPages)
+				urls.GET("/:id/pages/:pageId", urlHandler.GetCrawlPage)
 				urls.PUT("/:id/rerun", urlHandler.RerunAnalysis)
 				urls.DELETE("/:id", urlHandler.DeleteURL)
 				urls.POST("/bulk-delete", urlHandler.BulkDelete)
 				urls.POST("/bulk-rerun", urlHandler.BulkRerun)
+				urls.POST("/import-sitemap", urlHandler.ImportSitemap)
+				urls.GET("/import-sitemap/:importId", urlHandler.GetSitemapImport)
+			}
+
+			// User-defined technology fingerprints
+			technologies := protected.Group("/technologies")
+			{
+				technologies.GET("", technologyHandler.ListTechnologies)
+				technologies.POST("", technologyHandler.AddTechnology)
+				technologies.DELETE("/:id", technologyHandler.DeleteTechnology)
 			}
 		}
 	}
@@ -0,0 +1,336 @@
+package crawler
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"website-crawler/internal/models"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// wcagCriterion describes a WCAG 2.x success criterion
+type wcagCriterion struct {
+	Number string
+	Name   string
+	Level  string
+}
+
+var (
+	wcagNonTextContent    = wcagCriterion{"1.1.1", "Non-text Content", "A"}
+	wcagInfoRelationships = wcagCriterion{"1.3.1", "Info and Relationships", "A"}
+	wcagFocusOrder        = wcagCriterion{"2.4.3", "Focus Order", "A"}
+	wcagLinkPurpose       = wcagCriterion{"2.4.4", "Link Purpose (In Context)", "A"}
+	wcagLanguageOfPage    = wcagCriterion{"3.1.1", "Language of Page", "A"}
+	wcagLabels            = wcagCriterion{"3.3.2", "Labels or Instructions", "A"}
+	wcagNameRoleValue     = wcagCriterion{"4.1.2", "Name, Role, Value", "A"}
+)
+
+// genericLinkTexts are link texts that say nothing about the link's target
+var genericLinkTexts = map[string]bool{
+	"click here": true,
+	"click":      true,
+	"here":       true,
+	"more":       true,
+	"read more":  true,
+	"learn more": true,
+	"more info":  true,
+	"link":       true,
+	"this link":  true,
+	"go":         true,
+	"continue":   true,
+}
+
+// ariaRoles are the concrete WAI-ARIA 1.2 roles, plus the DPUB and graphics
+// module roles. Abstract roles such as "widget" are not valid in markup.
+var ariaRoles = map[string]bool{
+	"alert": true, "alertdialog": true, "application": true, "article": true,
+	"banner": true, "blockquote": true, "button": true, "caption": true,
+	"cell": true, "checkbox": true, "code": true, "columnheader": true,
+	"combobox": true, "complementary": true, "contentinfo": true, "definition": true,
+	"deletion": true, "dialog": true, "directory": true, "document": true,
+	"emphasis": true, "feed": true, "figure": true, "form": true,
+	"generic": true, "grid": true, "gridcell": true, "group": true,
+	"heading": true, "img": true, "insertion": true, "link": true,
+	"list": true, "listbox": true, "listitem": true, "log": true,
+	"main": true, "marquee": true, "math": true, "menu": true,
+	"menubar": true, "menuitem": true, "menuitemcheckbox": true, "menuitemradio": true,
+	"meter": true, "navigation": true, "none": true, "note": true,
+	"option": true, "paragraph": true, "presentation": true, "progressbar": true,
+	"radio": true, "radiogroup": true, "region": true, "row": true,
+	"rowgroup": true, "rowheader": true, "scrollbar": true, "search": true,
+	"searchbox": true, "separator": true, "slider": true, "spinbutton": true,
+	"status": true, "strong": true, "subscript": true, "superscript": true,
+	"switch": true, "tab": true, "table": true, "tablist": true,
+	"tabpanel": true, "term": true, "textbox": true, "time": true,
+	"timer": true, "toolbar": true, "tooltip": true, "tree": true,
+	"treegrid": true, "treeitem": true,
+	"graphics-document": true, "graphics-object": true, "graphics-symbol": true,
+}
+
+// ariaAttributes are the WAI-ARIA 1.2 states and properties. Attributes whose
+// value must be true or false are marked.
+var ariaAttributes = map[string]bool{
+	"aria-activedescendant": false, "aria-atomic": true, "aria-autocomplete": false,
+	"aria-braillelabel": false, "aria-brailleroledescription": false, "aria-busy": true,
+	"aria-checked": false, "aria-colcount": false, "aria-colindex": false,
+	"aria-colindextext": false, "aria-colspan": false, "aria-controls": false,
+	"aria-current": false, "aria-describedby": false, "aria-description": false,
+	"aria-details": false, "aria-disabled": true, "aria-dropeffect": false,
+	"aria-errormessage": false, "aria-expanded": false, "aria-flowto": false,
+	"aria-grabbed": false, "aria-haspopup": false, "aria-hidden": false,
+	"aria-invalid": false, "aria-keyshortcuts": false, "aria-label": false,
+	"aria-labelledby": false, "aria-level": false, "aria-live": false,
+	"aria-modal": true, "aria-multiline": true, "aria-multiselectable": true,
+	"aria-orientation": false, "aria-owns": false, "aria-placeholder": false,
+	"aria-posinset": false, "aria-pressed": false, "aria-readonly": true,
+	"aria-relevant": false, "aria-required": true, "aria-roledescription": false,
+	"aria-rowcount": false, "aria-rowindex": false, "aria-rowindextext": false,
+	"aria-rowspan": false, "aria-selected": false, "aria-setsize": false,
+	"aria-sort": false, "aria-valuemax": false, "aria-valuemin": false,
+	"aria-valuenow": false, "aria-valuetext": false,
+}
+
+// ariaIDRefAttributes reference other elements by id
+var ariaIDRefAttributes = []string{
+	"aria-labelledby", "aria-describedby", "aria-controls", "aria-owns",
+	"aria-activedescendant", "aria-errormessage", "aria-details", "aria-flowto",
+}
+
+// auditAccessibility checks a page for common WCAG failures that can be
+// detected from the markup alone
+func (c *CrawlerService) auditAccessibility(doc *goquery.Document) models.AccessibilityAudit {
+	audit := models.AccessibilityAudit{
+		Findings: []models.AccessibilityFinding{},
+		ByRule:   map[string]int{},
+	}
+	report := func(s *goquery.Selection, rule string, criterion wcagCriterion, severity, message string) {
+		audit.Findings = append(audit.Findings, models.AccessibilityFinding{
+			Rule:      rule,
+			Criterion: criterion.Number,
+			Name:      criterion.Name,
+			Level:     criterion.Level,
+			Severity:  severity,
+			Message:   message,
+			Element:   cssPath(s),
+		})
+		audit.ByRule[rule]++
+	}
+
+	// ids and label targets are indexed once, rather than searched for per element
+	ids := map[string]int{}
+	elements := map[string]*goquery.Selection{}
+	doc.Find("[id]").Each(func(i int, s *goquery.Selection) {
+		id := s.AttrOr("id", "")
+		ids[id]++
+		if _, ok := elements[id]; !ok {
+			// References resolve to the first element with an id
+			elements[id] = s
+		}
+	})
+	labelled := map[string]bool{}
+	referenced := map[string]bool{}
+	doc.Find("label[for]").Each(func(i int, s *goquery.Selection) {
+		labelled[s.AttrOr("for", "")] = true
+		referenced[s.AttrOr("for", "")] = true
+	})
+
+	// Page language
+	root := doc.Find("html").First()
+	if strings.TrimSpace(root.AttrOr("lang", "")) == "" {
+		report(root, "html_lang_missing", wcagLanguageOfPage, models.SeverityMedium, "The html element has no lang attribute")
+	}
+
+	// Images
+	doc.Find("img, area[href], input[type='image' i]").Each(func(i int, s *goquery.Selection) {
+		if _, hasAlt := s.Attr("alt"); hasAlt || hasARIALabel(s, ids) || isHiddenFromAT(s) {
+			return
+		}
+		if role := strings.TrimSpace(s.AttrOr("role", "")); goquery.NodeName(s) == "img" && (role == "presentation" || role == "none") {
+			return
+		}
+		report(s, "image_alt_missing", wcagNonTextContent, models.SeverityHigh, "The "+goquery.NodeName(s)+" has no alt text; use alt=\"\" if it is decorative")
+	})
+
+	// Form fields
+	doc.Find("input, select, textarea").Each(func(i int, s *goquery.Selection) {
+		if goquery.NodeName(s) == "input" {
+			switch strings.ToLower(s.AttrOr("type", "text")) {
+			case "hidden", "image":
+				return
+			case "submit", "reset", "button":
+				// Buttons are named by their value, or by the browser's default text
+				return
+			}
+		}
+		if isHiddenFromAT(s) || hasARIALabel(s, ids) || strings.TrimSpace(s.AttrOr("title", "")) != "" {
+			return
+		}
+		if s.ParentsFiltered("label").Length() > 0 {
+			return
+		}
+		if id := s.AttrOr("id", ""); id != "" && labelled[id] {
+			return
+		}
+		severity := models.SeverityHigh
+		message := "The form field has no label"
+		if strings.TrimSpace(s.AttrOr("placeholder", "")) != "" {
+			severity = models.SeverityMedium
+			message = "The form field is only labelled by its placeholder, which disappears when typing"
+		}
+		report(s, "form_label_missing", wcagLabels, severity, message)
+	})
+
+	// Links
+	doc.Find("a[href]").Each(func(i int, s *goquery.Selection) {
+		if isHiddenFromAT(s) {
+			return
+		}
+		text := accessibleText(s, elements)
+		switch {
+		case text == "":
+			report(s, "link_text_empty", wcagLinkPurpose, models.SeverityHigh, "The link has no text, so screen readers announce only its URL")
+		case genericLinkTexts[strings.Trim(strings.ToLower(text), " .:!>»→")]:
+			report(s, "link_text_generic", wcagLinkPurpose, models.SeverityLow, fmt.Sprintf("The link text %q does not describe its target", text))
+		}
+	})
+
+	// ARIA
+	doc.Find("*").Each(func(i int, s *goquery.Selection) {
+		node := s.Get(0)
+		if role, ok := nodeAttr(node, "role"); ok {
+			for _, token := range strings.Fields(strings.ToLower(role)) {
+				if !ariaRoles[token] && !strings.HasPrefix(token, "doc-") {
+					report(s, "aria_role_invalid", wcagNameRoleValue, models.SeverityMedium, fmt.Sprintf("%q is not a valid ARIA role", token))
+				}
+			}
+			if strings.TrimSpace(role) == "" {
+				report(s, "aria_role_invalid", wcagNameRoleValue, models.SeverityLow, "The role attribute is empty")
+			}
+		}
+
+		for _, attr := range node.Attr {
+			if !strings.HasPrefix(attr.Key, "aria-") {
+				continue
+			}
+			boolean, known := ariaAttributes[attr.Key]
+			value := strings.ToLower(strings.TrimSpace(attr.Val))
+			switch {
+			case !known:
+				report(s, "aria_attribute_invalid", wcagNameRoleValue, models.SeverityMedium, fmt.Sprintf("%s is not a valid ARIA attribute", attr.Key))
+			case boolean && value != "true" && value != "false":
+				report(s, "aria_attribute_invalid", wcagNameRoleValue, models.SeverityLow, fmt.Sprintf("%s must be true or false, not %q", attr.Key, attr.Val))
+			}
+		}
+
+		for _, name := range ariaIDRefAttributes {
+			refs, ok := nodeAttr(node, name)
+			if !ok {
+				continue
+			}
+			for _, ref := range strings.Fields(refs) {
+				referenced[ref] = true
+				if ids[ref] == 0 {
+					report(s, "aria_reference_missing", wcagNameRoleValue, models.SeverityMedium, fmt.Sprintf("%s refers to the id %q, which does not exist", name, ref))
+				}
+			}
+		}
+	})
+
+	// A duplicate id only matters when a label or ARIA attribute refers to
+	// it, since the reference then reaches just the first element
+	reported := map[string]bool{}
+	doc.Find("[id]").Each(func(i int, s *goquery.Selection) {
+		id := s.AttrOr("id", "")
+		if id != "" && ids[id] > 1 && referenced[id] && !reported[id] {
+			reported[id] = true
+			report(s, "duplicate_id_referenced", wcagNameRoleValue, models.SeverityMedium, fmt.Sprintf("The id %q is used by %d elements, so references to it reach only the first", id, ids[id]))
+		}
+	})
+
+	// Keyboard order
+	doc.Find("[tabindex]").Each(func(i int, s *goquery.Selection) {
+		if tabindex, err := strconv.Atoi(strings.TrimSpace(s.AttrOr("tabindex", ""))); err == nil && tabindex > 0 {
+			report(s, "tabindex_positive", wcagFocusOrder, models.SeverityMedium, fmt.Sprintf("tabindex=%d overrides the natural focus order", tabindex))
+		}
+	})
+
+	// Heading levels, following the same rule as the heading outline
+	previousLevel := 0
+	doc.Find("h1, h2, h3, h4, h5, h6").Each(func(i int, s *goquery.Selection) {
+		level := int(goquery.NodeName(s)[1] - '0')
+		if previousLevel > 0 && level > previousLevel+1 {
+			report(s, "heading_level_skipped", wcagInfoRelationships, models.SeverityLow, fmt.Sprintf("The h%d follows an h%d, skipping a level", level, previousLevel))
+		}
+		previousLevel = level
+	})
+
+	audit.Total = len(audit.Findings)
+	return audit
+}
+
+func (c *CrawlerService) accessibilityJSON(audit models.AccessibilityAudit) string {
+	if jsonData, err := json.Marshal(audit); err == nil {
+		return string(jsonData)
+	}
+	return "{}"
+}
+
+// hasARIALabel reports whether an element is named through aria-label or a
+// resolvable aria-labelledby
+func hasARIALabel(s *goquery.Selection, ids map[string]int) bool {
+	if strings.TrimSpace(s.AttrOr("aria-label", "")) != "" {
+		return true
+	}
+	for _, ref := range strings.Fields(s.AttrOr("aria-labelledby", "")) {
+		if ids[ref] > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// isHiddenFromAT reports whether an element is removed from the
+// accessibility tree by aria-hidden or the hidden attribute
+func isHiddenFromAT(s *goquery.Selection) bool {
+	return s.Parents().AddSelection(s).FilterFunction(func(i int, el *goquery.Selection) bool {
+		_, isHidden := el.Attr("hidden")
+		return isHidden || strings.EqualFold(strings.TrimSpace(el.AttrOr("aria-hidden", "")), "true")
+	}).Length() > 0
+}
+
+// accessibleText approximates the accessible name of a link from its
+// aria-labelledby, aria-label, text content, image alt text and title.
+// elements maps each id to the element it refers to.
+func accessibleText(s *goquery.Selection, elements map[string]*goquery.Selection) string {
+	var labels []string
+	for _, ref := range strings.Fields(s.AttrOr("aria-labelledby", "")) {
+		if el, ok := elements[ref]; ok {
+			if text := strings.Join(strings.Fields(el.Text()), " "); text != "" {
+				labels = append(labels, text)
+			}
+		}
+	}
+	if len(labels) > 0 {
+		return strings.Join(labels, " ")
+	}
+	if label := strings.TrimSpace(s.AttrOr("aria-label", "")); label != "" {
+		return label
+	}
+	if text := strings.Join(strings.Fields(s.Text()), " "); text != "" {
+		return text
+	}
+	var alts []string
+	s.Find("img[alt], svg title").Each(func(i int, el *goquery.Selection) {
+		alt := el.AttrOr("alt", el.Text())
+		if alt = strings.TrimSpace(alt); alt != "" {
+			alts = append(alts, alt)
+		}
+	})
+	if len(alts) > 0 {
+		return strings.Join(alts, " ")
+	}
+	return strings.TrimSpace(s.AttrOr("title", ""))
+}
@@ -0,0 +1,79 @@
+package crawler
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAuditAccessibility(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		byRule map[string]int
+	}{
+		{
+			"accessible",
+			`<img src="a.png" alt=""><img src="b.png" role="presentation">
+<label>Name <input name="name"></label>
+<label for="email">Email</label><input id="email" type="email">
+<input type="submit"><input type="hidden" name="token">
+<span id="more-label">Read the pricing guide</span><a href="/pricing" aria-labelledby="more-label">More</a>
+<a href="/"><img src="logo.png" alt="Home"></a>
+<div role="navigation" aria-expanded="false"></div>
+<h1>Title</h1><h2>Section</h2><h1>Next</h1>`,
+			map[string]int{},
+		},
+		{
+			"images and fields",
+			`<img src="a.png"><area href="/x"><div aria-hidden="true"><img src="hidden.png"></div>
+<input name="q" placeholder="Search"><textarea></textarea><input id="orphan"><label for="other">Other</label>`,
+			map[string]int{"image_alt_missing": 2, "form_label_missing": 3},
+		},
+		{
+			"links",
+			`<a href="/a"></a><a href="/b">Click here</a><a href="/c">read more.</a><a href="/d" hidden></a>`,
+			map[string]int{"link_text_empty": 1, "link_text_generic": 2},
+		},
+		{
+			"aria and focus",
+			`<div role="buton"></div><div role=""></div><div aria-hiddden="true"></div><div aria-modal="yes"></div>
+<div aria-describedby="nowhere"></div><button tabindex="2">Go</button><button tabindex="-1">Skip</button>`,
+			map[string]int{"aria_role_invalid": 2, "aria_attribute_invalid": 2, "aria_reference_missing": 1, "tabindex_positive": 1},
+		},
+		{
+			"structure",
+			`<h1>Title</h1><h3>Skipped</h3><p id="dup"></p><p id="dup"></p>
+<label for="field">Name</label><input id="field"><input id="field">
+<span id="name">Pricing</span><span id="name"></span><a href="/pricing" aria-labelledby="name"></a>`,
+			map[string]int{"heading_level_skipped": 1, "duplicate_id_referenced": 2},
+		},
+	}
+
+	c := NewCrawlerService()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			audit := c.auditAccessibility(newTestDocument(t, `<html lang="en"><body>`+tt.body+`</body></html>`))
+			if !reflect.DeepEqual(audit.ByRule, tt.byRule) {
+				t.Errorf("findings by rule %v, want %v: %+v", audit.ByRule, tt.byRule, audit.Findings)
+			}
+			if audit.Total != len(audit.Findings) {
+				t.Errorf("total %d, but %d findings", audit.Total, len(audit.Findings))
+			}
+		})
+	}
+}
+
+func TestAuditAccessibilityFinding(t *testing.T) {
+	audit := NewCrawlerService().auditAccessibility(newTestDocument(t, `<html><body><main id="content"><img src="a.png"></main></body></html>`))
+	if len(audit.Findings) != 2 {
+		t.Fatalf("got %d findings, want the missing lang and alt: %+v", len(audit.Findings), audit.Findings)
+	}
+
+	lang, alt := audit.Findings[0], audit.Findings[1]
+	if lang.Rule != "html_lang_missing" || lang.Criterion != "3.1.1" || lang.Element != "html" {
+		t.Errorf("lang finding %+v", lang)
+	}
+	if alt.Rule != "image_alt_missing" || alt.Criterion != "1.1.1" || alt.Name != "Non-text Content" || alt.Level != "A" || alt.Element != "main#content > img" {
+		t.Errorf("alt finding %+v", alt)
+	}
+}
@@ -0,0 +1,288 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"website-crawler/internal/models"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/publicsuffix"
+)
+
+// assetTypes are the resource types that count towards a page's weight
+var assetTypes = map[string]bool{
+	models.ResourceScript:     true,
+	models.ResourceStylesheet: true,
+	models.ResourceImage:      true,
+	models.ResourceFont:       true,
+	models.ResourceMedia:      true,
+}
+
+var (
+	fontFaceRule = regexp.MustCompile(`(?is)@font-face\s*\{[^}]*\}`)
+	cssURL       = regexp.MustCompile(`url\(\s*['"]?([^'")]+)['"]?\s*\)`)
+)
+
+// inventoryAssets lists the assets a page loads with their sizes and adds
+// them up to the page weight. Sizes come from the link check where the
+// server declared them; stylesheets are always fetched so their fonts can be
+// found, and other assets are fetched only when their size is unknown.
+func (c *CrawlerService) inventoryAssets(ctx context.Context, doc *goquery.Document, pageURL *url.URL, links linkAnalysis, htmlBytes int64, throttle requestThrottle) models.AssetInventory {
+	inventory := models.AssetInventory{
+		Assets:    []models.Asset{},
+		HTMLBytes: htmlBytes,
+		ByType:    map[string]models.AssetTypeSummary{},
+	}
+
+	seen := map[string]bool{}
+	add := func(assetURL *url.URL, assetType string) {
+		key := assetURL.String()
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+
+		asset := models.Asset{
+			URL:        key,
+			Type:       assetType,
+			Size:       -1,
+			ThirdParty: isThirdParty(pageURL, assetURL),
+		}
+		if check, ok := links.Checked[key]; ok && check.Checked && check.Err == nil && check.StatusCode < 400 {
+			asset.ContentType = check.ContentType
+			if check.Size >= 0 && assetType != models.ResourceStylesheet {
+				asset.Size = check.Size
+				asset.SizeSource = models.SizeFromContentLength
+			}
+		}
+		inventory.Assets = append(inventory.Assets, asset)
+	}
+
+	for _, ref := range links.Refs {
+		if assetTypes[ref.Type] {
+			add(ref.URL, ref.Type)
+		}
+	}
+	for _, fontURL := range pageFonts(doc, pageURL) {
+		add(fontURL, models.ResourceFont)
+	}
+
+	if c.linkChecker.budget > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.linkChecker.budget)
+		defer cancel()
+	}
+
+	// Stylesheets first, since the fonts they declare are assets too
+	var stylesheets []int
+	for i, asset := range inventory.Assets {
+		if asset.Type == models.ResourceStylesheet {
+			stylesheets = append(stylesheets, i)
+		}
+	}
+	var fontsMutex sync.Mutex
+	var fonts []*url.URL
+	c.measureAssets(ctx, inventory.Assets, stylesheets, throttle, func(asset *models.Asset, body []byte) {
+		sheetURL, err := url.Parse(asset.URL)
+		if err != nil {
+			return
+		}
+		fontsMutex.Lock()
+		fonts = append(fonts, stylesheetFonts(string(body), sheetURL)...)
+		fontsMutex.Unlock()
+	})
+	for _, fontURL := range fonts {
+		add(fontURL, models.ResourceFont)
+	}
+
+	var unknown []int
+	for i, asset := range inventory.Assets {
+		if asset.Size < 0 && asset.Type != models.ResourceStylesheet {
+			unknown = append(unknown, i)
+		}
+	}
+	c.measureAssets(ctx, inventory.Assets, unknown, throttle, nil)
+
+	inventory.TotalBytes = htmlBytes
+	inventory.Requests = 1 + len(inventory.Assets)
+	for _, asset := range inventory.Assets {
+		summary := inventory.ByType[asset.Type]
+		summary.Count++
+		if asset.Size < 0 {
+			inventory.Unmeasured++
+		} else {
+			summary.Bytes += asset.Size
+			inventory.TotalBytes += asset.Size
+			if asset.ThirdParty {
+				inventory.ThirdPartyBytes += asset.Size
+			}
+		}
+		inventory.ByType[asset.Type] = summary
+	}
+
+	return inventory
+}
+
+func (c *CrawlerService) assetsJSON(inventory models.AssetInventory) string {
+	if jsonData, err := json.Marshal(inventory); err == nil {
+		return string(jsonData)
+	}
+	return "{}"
+}
+
+// measureAssets fetches the assets at the given indexes with the link
+// checker's concurrency limits and the throttle, if any, recording their
+// transferred size. onBody, if set, receives each decoded body.
+func (c *CrawlerService) measureAssets(ctx context.Context, assets []models.Asset, indexes []int, throttle requestThrottle, onBody func(asset *models.Asset, body []byte)) {
+	lc := c.linkChecker
+	forEachIndex(len(indexes), lc.concurrency, func(i int) {
+		asset := &assets[indexes[i]]
+		assetURL, err := url.Parse(asset.URL)
+		if err != nil {
+			return
+		}
+
+		release, ok := lc.acquireHost(ctx, assetURL.Host)
+		if !ok {
+			return
+		}
+		if throttle != nil && !throttle(ctx, assetURL) {
+			release()
+			return
+		}
+		size, contentType, body, err := c.fetchAsset(ctx, assetURL, onBody != nil)
+		release()
+		if err != nil {
+			return
+		}
+
+		asset.Size = size
+		asset.SizeSource = models.SizeFromFetch
+		if contentType != "" {
+			asset.ContentType = contentType
+		}
+		if onBody != nil {
+			onBody(asset, body)
+		}
+	})
+}
+
+// fetchAsset downloads an asset and returns its size on the wire. The body is
+// only kept, decompressed, when keepBody is set.
+func (c *CrawlerService) fetchAsset(ctx context.Context, assetURL *url.URL, keepBody bool) (int64, string, []byte, error) {
+	if c.linkChecker.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.linkChecker.timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, assetURL.String(), nil)
+	if err != nil {
+		return 0, "", nil, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; WebsiteCrawler/1.0)")
+	// Asking for gzip explicitly stops the transport from decompressing, so
+	// the compressed size is what gets counted
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	resp, err := c.linkChecker.client.Do(req)
+	if err != nil {
+		return 0, "", nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return 0, "", nil, fmt.Errorf("asset returned status %d", resp.StatusCode)
+	}
+
+	limit := c.assetFetchLimit
+	if limit <= 0 {
+		limit = DefaultConfig().AssetFetchLimit
+	}
+	contentType := resp.Header.Get("Content-Type")
+
+	if !keepBody {
+		size, err := io.Copy(io.Discard, io.LimitReader(resp.Body, limit+1))
+		if err != nil {
+			return 0, "", nil, err
+		}
+		if size > limit {
+			return 0, "", nil, fmt.Errorf("asset is larger than %d bytes", limit)
+		}
+		return size, contentType, nil, nil
+	}
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return 0, "", nil, err
+	}
+	if int64(len(raw)) > limit {
+		return 0, "", nil, fmt.Errorf("asset is larger than %d bytes", limit)
+	}
+
+	body := raw
+	if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		reader, err := gzip.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return 0, "", nil, err
+		}
+		if body, err = io.ReadAll(io.LimitReader(reader, limit)); err != nil {
+			return 0, "", nil, err
+		}
+	}
+	return int64(len(raw)), contentType, body, nil
+}
+
+// pageFonts returns the fonts a page preloads or declares in inline styles
+func pageFonts(doc *goquery.Document, pageURL *url.URL) []*url.URL {
+	var fonts []*url.URL
+	doc.Find("link[href]").Each(func(i int, s *goquery.Selection) {
+		if hasRelToken(s, "preload") && strings.EqualFold(s.AttrOr("as", ""), "font") {
+			if fontURL, ok := resolveReference(pageURL, s.AttrOr("href", "")); ok {
+				fonts = append(fonts, fontURL)
+			}
+		}
+	})
+	doc.Find("style").Each(func(i int, s *goquery.Selection) {
+		fonts = append(fonts, stylesheetFonts(s.Text(), pageURL)...)
+	})
+	return fonts
+}
+
+// stylesheetFonts returns the font files referenced by the @font-face rules
+// of a stylesheet, resolved against the stylesheet's URL
+func stylesheetFonts(css string, sheetURL *url.URL) []*url.URL {
+	var fonts []*url.URL
+	for _, rule := range fontFaceRule.FindAllString(css, -1) {
+		for _, match := range cssURL.FindAllStringSubmatch(rule, -1) {
+			if fontURL, ok := resolveReference(sheetURL, match[1]); ok {
+				fonts = append(fonts, fontURL)
+			}
+		}
+	}
+	return fonts
+}
+
+// isThirdParty reports whether an asset is served from a different site than
+// the page, treating subdomains of the same registrable domain as first party
+func isThirdParty(pageURL, assetURL *url.URL) bool {
+	pageSite, err := publicsuffix.EffectiveTLDPlusOne(pageURL.Hostname())
+	if err != nil {
+		return assetURL.Hostname() != pageURL.Hostname()
+	}
+	assetSite, err := publicsuffix.EffectiveTLDPlusOne(assetURL.Hostname())
+	if err != nil {
+		return true
+	}
+	return assetSite != pageSite
+}
@@ -0,0 +1,129 @@
+package crawler
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"website-crawler/internal/models"
+)
+
+func TestInventoryAssets(t *testing.T) {
+	script := strings.Repeat("x", 1000)
+	image := strings.Repeat("i", 3000)
+	css := `body { color: red } @font-face { font-family: "Body"; src: url("fonts/body.woff2") format("woff2"); }`
+	font := strings.Repeat("f", 500)
+	page := `<html><head>
+<script src="/app.js"></script>
+<link rel="stylesheet" href="/css/site.css">
+<link rel="preload" as="font" href="/fonts/heading.woff2">
+</head><body><img src="/hero.jpg"><img src="/hero.jpg"><a href="/about">links are not assets</a></body></html>`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(page))
+		case "/app.js":
+			w.Header().Set("Content-Type", "application/javascript")
+			w.Header().Set("Content-Length", strconv.Itoa(len(script)))
+			w.Write([]byte(script))
+		case "/hero.jpg":
+			// Streamed, so the size is only known once the body is fetched
+			w.Header().Set("Content-Type", "image/jpeg")
+			w.(http.Flusher).Flush()
+			w.Write([]byte(image))
+		case "/css/site.css":
+			w.Header().Set("Content-Type", "text/css")
+			w.Header().Set("Content-Encoding", "gzip")
+			gz := gzip.NewWriter(w)
+			gz.Write([]byte(css))
+			gz.Close()
+		case "/css/fonts/body.woff2", "/fonts/heading.woff2":
+			w.Header().Set("Content-Length", strconv.Itoa(len(font)))
+			w.Write([]byte(font))
+		case "/about":
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	analysis, err := NewCrawlerService().CrawlWebsite(context.Background(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var inventory models.AssetInventory
+	if err := json.Unmarshal([]byte(analysis.Assets), &inventory); err != nil {
+		t.Fatalf("assets %q: %v", analysis.Assets, err)
+	}
+
+	sizes := map[string]int64{}
+	sources := map[string]string{}
+	for _, asset := range inventory.Assets {
+		path := strings.TrimPrefix(asset.URL, server.URL)
+		sizes[path] = asset.Size
+		sources[path] = asset.SizeSource
+	}
+
+	if len(inventory.Assets) != 5 {
+		t.Fatalf("got %d assets, want 5: %+v", len(inventory.Assets), inventory.Assets)
+	}
+	if sizes["/app.js"] != int64(len(script)) || sources["/app.js"] != models.SizeFromContentLength {
+		t.Errorf("script measured as %d from %s", sizes["/app.js"], sources["/app.js"])
+	}
+	if sizes["/hero.jpg"] != int64(len(image)) || sources["/hero.jpg"] != models.SizeFromFetch {
+		t.Errorf("image measured as %d from %s", sizes["/hero.jpg"], sources["/hero.jpg"])
+	}
+	if sizes["/css/site.css"] <= 0 || sizes["/css/site.css"] == int64(len(css)) || sources["/css/site.css"] != models.SizeFromFetch {
+		t.Errorf("stylesheet should be counted compressed: %d from %s", sizes["/css/site.css"], sources["/css/site.css"])
+	}
+	if sizes["/css/fonts/body.woff2"] != int64(len(font)) || sizes["/fonts/heading.woff2"] != int64(len(font)) {
+		t.Errorf("fonts measured as %d and %d", sizes["/css/fonts/body.woff2"], sizes["/fonts/heading.woff2"])
+	}
+
+	var total int64
+	for _, size := range sizes {
+		total += size
+	}
+	total += int64(len(page))
+	if inventory.TotalBytes != total || analysis.PageWeight != total || inventory.HTMLBytes != int64(len(page)) {
+		t.Errorf("page weight %d (stored %d, html %d), want %d", inventory.TotalBytes, analysis.PageWeight, inventory.HTMLBytes, total)
+	}
+	if inventory.Requests != 6 || analysis.RequestCount != 6 || inventory.Unmeasured != 0 || inventory.ThirdPartyBytes != 0 {
+		t.Errorf("requests %d, unmeasured %d, third party bytes %d", inventory.Requests, inventory.Unmeasured, inventory.ThirdPartyBytes)
+	}
+	if fonts := inventory.ByType[models.ResourceFont]; fonts.Count != 2 || fonts.Bytes != int64(2*len(font)) {
+		t.Errorf("font summary %+v", fonts)
+	}
+}
+
+func TestIsThirdParty(t *testing.T) {
+	tests := []struct {
+		page  string
+		asset string
+		want  bool
+	}{
+		{"https://www.example.com/", "https://example.com/a.js", false},
+		{"https://www.example.com/", "https://cdn.example.com/a.js", false},
+		{"https://www.example.co.uk/", "https://static.example.co.uk/a.js", false},
+		{"https://www.example.com/", "https://cdn.example.net/a.js", true},
+		{"https://a.github.io/", "https://b.github.io/a.js", true},
+		{"http://localhost:8080/", "http://localhost:9090/a.js", false},
+	}
+
+	for _, tt := range tests {
+		pageURL, _ := url.Parse(tt.page)
+		assetURL, _ := url.Parse(tt.asset)
+		if got := isThirdParty(pageURL, assetURL); got != tt.want {
+			t.Errorf("isThirdParty(%s, %s) = %v, want %v", tt.page, tt.asset, got, tt.want)
+		}
+	}
+}
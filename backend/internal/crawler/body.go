@@ -0,0 +1,165 @@
+package crawler
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"website-crawler/internal/models"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/text/transform"
+)
+
+// limitedBody reads at most limit bytes of a response body, recording
+// whether the body went on past them
+type limitedBody struct {
+	reader    io.Reader
+	limit     int64
+	read      int64
+	probed    bool
+	truncated bool
+	// done is when the body was read to its end, before any parsing of it finished
+	done time.Time
+}
+
+func (l *limitedBody) Read(p []byte) (int, error) {
+	if l.read >= l.limit {
+		// One more byte tells a body that ends exactly at the limit from a longer one
+		if !l.probed {
+			l.probed = true
+			var probe [1]byte
+			if n, _ := io.ReadFull(l.reader, probe[:]); n > 0 {
+				l.truncated = true
+			}
+		}
+		l.finish()
+		return 0, io.EOF
+	}
+
+	if remaining := l.limit - l.read; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := l.reader.Read(p)
+	l.read += int64(n)
+	if err == io.EOF {
+		l.finish()
+	}
+	return n, err
+}
+
+func (l *limitedBody) finish() {
+	if l.done.IsZero() {
+		l.done = time.Now()
+	}
+}
+
+// doneAt returns when the body was read to its end, or now if it never was
+func (l *limitedBody) doneAt() time.Time {
+	if l.done.IsZero() {
+		return time.Now()
+	}
+	return l.done
+}
+
+// maxSourceBytes caps the decoded markup kept alongside the parsed document.
+// Only source line lookups and technology patterns read it, and both are
+// limited to this start of the page.
+const maxSourceBytes = 2 << 20
+
+// sourceBuffer keeps the first limit bytes written to it and drops the rest.
+// It builds a string directly, so the source is never copied a second time.
+type sourceBuffer struct {
+	strings.Builder
+	limit int
+}
+
+func (s *sourceBuffer) Write(p []byte) (int, error) {
+	if remaining := s.limit - s.Len(); remaining > 0 {
+		s.Builder.Write(p[:min(len(p), remaining)])
+	}
+	return len(p), nil
+}
+
+// bodyReader streams a page body through the size limit, keeping enough of
+// its start buffered to classify it and find its character encoding
+type bodyReader struct {
+	*bufio.Reader
+	limited   *limitedBody
+	validator *utf8Validator
+}
+
+func newBodyReader(body io.Reader, limit int64) *bodyReader {
+	limited := &limitedBody{reader: body, limit: limit}
+	validator := &utf8Validator{}
+	return &bodyReader{
+		Reader:    bufio.NewReaderSize(io.TeeReader(limited, validator), charsetPrescanBytes),
+		limited:   limited,
+		validator: validator,
+	}
+}
+
+// prefix returns the start of the body without consuming it
+func (b *bodyReader) prefix() []byte {
+	prefix, _ := b.Peek(charsetPrescanBytes)
+	return prefix
+}
+
+// documentType classifies a response by its Content-Type, or by sniffing the
+// start of the body when the server did not send one
+func documentType(contentType string, body *bodyReader) string {
+	if strings.TrimSpace(contentType) == "" {
+		contentType = http.DetectContentType(body.prefix())
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return models.DocumentOther
+	}
+
+	switch {
+	case mediaType == "text/html", mediaType == "application/xhtml+xml":
+		return models.DocumentHTML
+	case mediaType == "application/pdf":
+		return models.DocumentPDF
+	case strings.HasPrefix(mediaType, "image/"):
+		return models.DocumentImage
+	case strings.HasPrefix(mediaType, "text/"), mediaType == "application/json", strings.HasSuffix(mediaType, "+json"),
+		mediaType == "application/xml", strings.HasSuffix(mediaType, "+xml"), mediaType == "application/javascript":
+		return models.DocumentText
+	}
+	return models.DocumentOther
+}
+
+// parseHTML decodes the body to UTF-8 and parses it as it streams in,
+// returning the document along with the first maxSourceBytes of its decoded
+// source
+func parseHTML(body *bodyReader, contentType string) (*goquery.Document, string, charsetInfo, error) {
+	prefix := body.prefix()
+	enc, charset := detectCharset(prefix, contentType, prescanMetaCharset(prefix))
+
+	source := &sourceBuffer{limit: maxSourceBytes}
+	decoded := io.TeeReader(transform.NewReader(body, enc.NewDecoder()), source)
+	doc, err := goquery.NewDocumentFromReader(decoded)
+	if err != nil {
+		return nil, "", charset, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+	// The parser stops at EOF, but make sure the size and validity cover the whole body
+	if _, err := io.Copy(io.Discard, decoded); err != nil {
+		return nil, "", charset, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	declared := charset.Source == models.CharsetSourceHeader || charset.Source == models.CharsetSourceMeta
+	switch {
+	case charset.Charset == "utf-8" && charset.Source != models.CharsetSourceSniffed && !body.validator.valid(body.limited.truncated):
+		// Declared as UTF-8 but not encoded as such
+		charset.Mismatch = true
+	case declared && isSingleByte(enc) && body.validator.multibyteUTF8(body.limited.truncated):
+		// Declared as a single-byte encoding but actually UTF-8
+		charset.Mismatch = true
+	}
+	return doc, source.String(), charset, nil
+}
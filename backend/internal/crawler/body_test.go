@@ -0,0 +1,156 @@
+package crawler
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"website-crawler/internal/models"
+)
+
+func TestLimitedBody(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		limit     int64
+		want      string
+		truncated bool
+	}{
+		{"shorter than the limit", "hello", 10, "hello", false},
+		{"exactly the limit", "hello", 5, "hello", false},
+		{"longer than the limit", "hello world", 5, "hello", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limited := &limitedBody{reader: iotest.OneByteReader(strings.NewReader(tt.body)), limit: tt.limit}
+			got, err := io.ReadAll(limited)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want || limited.truncated != tt.truncated || limited.read != int64(len(tt.want)) {
+				t.Errorf("read %q (%d bytes, truncated %v), want %q (truncated %v)", got, limited.read, limited.truncated, tt.want, tt.truncated)
+			}
+			if limited.done.IsZero() {
+				t.Error("reading to the end should record when the body was done")
+			}
+		})
+	}
+}
+
+func TestSourceBuffer(t *testing.T) {
+	source := &sourceBuffer{limit: 8}
+	for _, chunk := range []string{"<html>", "<body>", "</body>"} {
+		if n, err := source.Write([]byte(chunk)); n != len(chunk) || err != nil {
+			t.Fatalf("Write(%q) = %d, %v", chunk, n, err)
+		}
+	}
+	if got := source.String(); got != "<html><b" {
+		t.Errorf("kept %q, want the first 8 bytes", got)
+	}
+}
+
+func TestDocumentType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		body        string
+		want        string
+	}{
+		{"text/html; charset=utf-8", "", models.DocumentHTML},
+		{"application/xhtml+xml", "", models.DocumentHTML},
+		{"application/pdf", "", models.DocumentPDF},
+		{"image/svg+xml", "", models.DocumentImage},
+		{"text/plain", "", models.DocumentText},
+		{"application/ld+json", "", models.DocumentText},
+		{"application/octet-stream", "", models.DocumentOther},
+		{"not a media type;;", "", models.DocumentOther},
+		{"", "<!DOCTYPE html><html></html>", models.DocumentHTML},
+		{"", "%PDF-1.7\n", models.DocumentPDF},
+		{"", "\x89PNG\r\n\x1a\n", models.DocumentImage},
+	}
+
+	for _, tt := range tests {
+		body := newBodyReader(strings.NewReader(tt.body), 1<<20)
+		if got := documentType(tt.contentType, body); got != tt.want {
+			t.Errorf("documentType(%q, %q) = %q, want %q", tt.contentType, tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestParseHTML(t *testing.T) {
+	// Enough markup to push the body past the prescan window
+	filler := "<p>" + strings.Repeat("filler ", 300) + "</p>"
+
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		limit       int64
+		title       string
+		charset     string
+		mismatch    bool
+	}{
+		{"utf-8", "<title>Caf\xC3\xA9</title>" + filler, "text/html; charset=utf-8", 1 << 20, "Café", "utf-8", false},
+		{"utf-8 byte order mark", "\xEF\xBB\xBF<title>Caf\xC3\xA9</title>" + filler, "text/html", 1 << 20, "Café", "utf-8", false},
+		{"windows-1252 meta", `<meta charset="windows-1252"><title>Caf` + "\xE9</title>" + filler, "text/html", 1 << 20, "Café", "windows-1252", false},
+		{"invalid utf-8 after the prescan", "<title>Menu</title>" + filler + "<p>caf\xE9</p>", "text/html; charset=utf-8", 1 << 20, "Menu", "utf-8", true},
+		{"windows-1252 meta over utf-8", `<meta charset="windows-1252"><title>Caf` + "\xC3\xA9</title>" + filler, "text/html", 1 << 20, "CafÃ©", "windows-1252", true},
+		{"iso-8859-1 header over ascii", "<title>Menu</title>" + filler, "text/html; charset=iso-8859-1", 1 << 20, "Menu", "windows-1252", false},
+		{"character cut by the limit", "<title>Caf\xC3\xA9</title>", "text/html; charset=utf-8", 17, "Caf", "utf-8", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := newBodyReader(iotest.HalfReader(strings.NewReader(tt.body)), tt.limit)
+			doc, source, charset, err := parseHTML(body, tt.contentType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if title := doc.Find("title").Text(); !strings.HasPrefix(title, tt.title) {
+				t.Errorf("title %q, want %q", title, tt.title)
+			}
+			if charset.Charset != tt.charset || charset.Mismatch != tt.mismatch {
+				t.Errorf("charset %+v, want %s with mismatch %v", charset, tt.charset, tt.mismatch)
+			}
+			if body.limited.read != min(tt.limit, int64(len(tt.body))) {
+				t.Errorf("read %d bytes of %d", body.limited.read, len(tt.body))
+			}
+			if strings.HasPrefix(source, "\uFEFF") || !strings.Contains(source, "<title>") {
+				t.Errorf("source should be the decoded markup without a byte order mark: %.40q", source)
+			}
+		})
+	}
+}
+
+func TestUTF8Validator(t *testing.T) {
+	tests := []struct {
+		name      string
+		chunks    []string
+		truncated bool
+		want      bool
+		multibyte bool
+	}{
+		{"ascii", []string{"hello"}, false, true, false},
+		{"character split across writes", []string{"caf\xC3", "\xA9!"}, false, true, true},
+		{"four bytes split three ways", []string{"\xF0\x9F", "\x98", "\x80"}, false, true, true},
+		{"invalid byte", []string{"caf\xE9"}, false, false, false},
+		{"incomplete at the end", []string{"caf\xC3"}, false, false, false},
+		{"incomplete at a truncated end", []string{"caf\xC3"}, true, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := &utf8Validator{}
+			for _, chunk := range tt.chunks {
+				validator.Write([]byte(chunk))
+			}
+			if got := validator.valid(tt.truncated); got != tt.want {
+				t.Errorf("valid = %v, want %v", got, tt.want)
+			}
+			if got := validator.multibyteUTF8(tt.truncated); got != tt.multibyte {
+				t.Errorf("multibyteUTF8 = %v, want %v", got, tt.multibyte)
+			}
+		})
+	}
+}
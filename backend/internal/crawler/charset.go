@@ -0,0 +1,192 @@
+package crawler
+
+import (
+	"bytes"
+	"mime"
+	"strings"
+	"unicode/utf8"
+
+	"website-crawler/internal/models"
+
+	nethtml "golang.org/x/net/html"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// charsetPrescanBytes is how far into the document a meta charset is looked
+// for, as in the HTML encoding sniffing algorithm
+const charsetPrescanBytes = 1024
+
+// charsetInfo describes how a page's bytes were decoded
+type charsetInfo struct {
+	Charset  string
+	Source   string
+	Header   string
+	Meta     string
+	Mismatch bool
+}
+
+// detectCharset picks the character encoding of a body from its first bytes.
+// The encoding is taken from a byte order mark, the Content-Type header, the
+// document's meta charset, or sniffed, in that order.
+func detectCharset(prefix []byte, contentType, metaCharset string) (encoding.Encoding, charsetInfo) {
+	info := charsetInfo{Meta: metaCharset}
+	var enc encoding.Encoding
+
+	if _, params, err := mime.ParseMediaType(contentType); err == nil {
+		info.Header = strings.TrimSpace(params["charset"])
+	}
+
+	headerEnc, headerName := lookupCharset(info.Header)
+	metaEnc, metaName := lookupCharset(info.Meta)
+
+	switch {
+	case bytes.HasPrefix(prefix, []byte("\xEF\xBB\xBF")):
+		enc, info.Charset, info.Source = unicode.UTF8BOM, "utf-8", models.CharsetSourceBOM
+	case bytes.HasPrefix(prefix, []byte("\xFE\xFF")):
+		enc, info.Charset, info.Source = unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM), "utf-16be", models.CharsetSourceBOM
+	case bytes.HasPrefix(prefix, []byte("\xFF\xFE")):
+		enc, info.Charset, info.Source = unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM), "utf-16le", models.CharsetSourceBOM
+	case headerEnc != nil:
+		enc, info.Charset, info.Source = headerEnc, headerName, models.CharsetSourceHeader
+	case metaEnc != nil:
+		enc, info.Charset, info.Source = metaEnc, metaName, models.CharsetSourceMeta
+	case utf8.Valid(prefix[:completeRunes(prefix)]):
+		enc, info.Charset, info.Source = unicode.UTF8, "utf-8", models.CharsetSourceSniffed
+	default:
+		// Browsers fall back to windows-1252 for undeclared legacy pages
+		enc, _ = htmlindex.Get("windows-1252")
+		info.Charset, info.Source = "windows-1252", models.CharsetSourceSniffed
+	}
+
+	// A meta tag cannot declare UTF-16, since it is only readable in an ASCII-compatible encoding
+	if info.Source == models.CharsetSourceMeta && strings.HasPrefix(info.Charset, "utf-16") {
+		enc, info.Charset = unicode.UTF8, "utf-8"
+	}
+
+	switch {
+	case headerName != "" && metaName != "" && headerName != metaName:
+		info.Mismatch = true
+	case info.Header != "" && headerEnc == nil, info.Meta != "" && metaEnc == nil:
+		// The declared label is not an encoding browsers know
+		info.Mismatch = true
+	case info.Source == models.CharsetSourceBOM && (headerName != "" && headerName != info.Charset || metaName != "" && metaName != info.Charset):
+		// The byte order mark wins over any declaration that disagrees with it
+		info.Mismatch = true
+	}
+
+	return enc, info
+}
+
+// lookupCharset resolves a charset label to an encoding and its canonical name
+func lookupCharset(label string) (encoding.Encoding, string) {
+	if label == "" {
+		return nil, ""
+	}
+	enc, err := htmlindex.Get(label)
+	if err != nil {
+		return nil, ""
+	}
+	name, err := htmlindex.Name(enc)
+	if err != nil {
+		return nil, ""
+	}
+	return enc, name
+}
+
+// isSingleByte reports whether an encoding maps every byte to one character
+func isSingleByte(enc encoding.Encoding) bool {
+	_, ok := enc.(*charmap.Charmap)
+	return ok
+}
+
+// prescanMetaCharset returns the charset declared by a <meta charset> or
+// <meta http-equiv="Content-Type"> tag at the start of a document
+func prescanMetaCharset(prefix []byte) string {
+	tokenizer := nethtml.NewTokenizer(bytes.NewReader(prefix))
+	for {
+		switch tokenizer.Next() {
+		case nethtml.ErrorToken:
+			return ""
+		case nethtml.StartTagToken, nethtml.SelfClosingTagToken:
+			token := tokenizer.Token()
+			if token.Data != "meta" {
+				continue
+			}
+
+			var httpEquiv, content string
+			for _, attr := range token.Attr {
+				switch strings.ToLower(attr.Key) {
+				case "charset":
+					return strings.TrimSpace(attr.Val)
+				case "http-equiv":
+					httpEquiv = strings.ToLower(strings.TrimSpace(attr.Val))
+				case "content":
+					content = attr.Val
+				}
+			}
+			if httpEquiv == "content-type" {
+				if _, params, err := mime.ParseMediaType(content); err == nil && params["charset"] != "" {
+					return strings.TrimSpace(params["charset"])
+				}
+			}
+		}
+	}
+}
+
+// completeRunes returns the length of b without a multi-byte character cut
+// off at its end
+func completeRunes(b []byte) int {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				return i
+			}
+			break
+		}
+	}
+	return len(b)
+}
+
+// utf8Validator checks that the bytes written to it are valid UTF-8 without
+// keeping them, so a body can be checked as it streams past
+type utf8Validator struct {
+	pending []byte
+	invalid bool
+	// multibyte is whether any character outside ASCII was seen
+	multibyte bool
+}
+
+func (v *utf8Validator) Write(p []byte) (int, error) {
+	if v.invalid {
+		return len(p), nil
+	}
+	if !v.multibyte && bytes.IndexFunc(p, func(r rune) bool { return r >= utf8.RuneSelf }) >= 0 {
+		v.multibyte = true
+	}
+	data := p
+	if len(v.pending) > 0 {
+		data = append(v.pending, p...)
+	}
+	cut := completeRunes(data)
+	if !utf8.Valid(data[:cut]) {
+		v.invalid = true
+	}
+	v.pending = append(v.pending[:0], data[cut:]...)
+	return len(p), nil
+}
+
+// valid reports whether everything written was valid UTF-8. A character left
+// incomplete is only forgiven when the body was cut short.
+func (v *utf8Validator) valid(truncated bool) bool {
+	return !v.invalid && (len(v.pending) == 0 || truncated)
+}
+
+// multibyteUTF8 reports whether everything written was valid UTF-8 with at
+// least one character outside ASCII, which a legacy encoding would turn into
+// mojibake
+func (v *utf8Validator) multibyteUTF8(truncated bool) bool {
+	return v.multibyte && v.valid(truncated)
+}
@@ -0,0 +1,126 @@
+package crawler
+
+import (
+	"testing"
+
+	"website-crawler/internal/models"
+)
+
+func TestDetectCharset(t *testing.T) {
+	tests := []struct {
+		name        string
+		prefix      string
+		contentType string
+		meta        string
+		want        charsetInfo
+	}{
+		{
+			name:   "utf-8 byte order mark",
+			prefix: "\xEF\xBB\xBF<html>",
+			want:   charsetInfo{Charset: "utf-8", Source: models.CharsetSourceBOM},
+		},
+		{
+			name:   "utf-16le byte order mark",
+			prefix: "\xFF\xFE<\x00h\x00",
+			want:   charsetInfo{Charset: "utf-16le", Source: models.CharsetSourceBOM},
+		},
+		{
+			name:        "byte order mark disagreeing with the header",
+			prefix:      "\xFE\xFF\x00<",
+			contentType: "text/html; charset=utf-8",
+			want:        charsetInfo{Charset: "utf-16be", Source: models.CharsetSourceBOM, Header: "utf-8", Mismatch: true},
+		},
+		{
+			name:        "header",
+			prefix:      "<html>",
+			contentType: "text/html; charset=ISO-8859-1",
+			want:        charsetInfo{Charset: "windows-1252", Source: models.CharsetSourceHeader, Header: "ISO-8859-1"},
+		},
+		{
+			name:        "header wins over meta",
+			prefix:      "<html>",
+			contentType: "text/html; charset=utf-8",
+			meta:        "shift_jis",
+			want:        charsetInfo{Charset: "utf-8", Source: models.CharsetSourceHeader, Header: "utf-8", Meta: "shift_jis", Mismatch: true},
+		},
+		{
+			name:        "header and meta agree under different labels",
+			prefix:      "<html>",
+			contentType: "text/html; charset=latin1",
+			meta:        "windows-1252",
+			want:        charsetInfo{Charset: "windows-1252", Source: models.CharsetSourceHeader, Header: "latin1", Meta: "windows-1252"},
+		},
+		{
+			name:   "meta",
+			prefix: "<html>",
+			meta:   "euc-kr",
+			want:   charsetInfo{Charset: "euc-kr", Source: models.CharsetSourceMeta, Meta: "euc-kr"},
+		},
+		{
+			name:   "meta declaring utf-16 means utf-8",
+			prefix: "<html>",
+			meta:   "utf-16",
+			want:   charsetInfo{Charset: "utf-8", Source: models.CharsetSourceMeta, Meta: "utf-16"},
+		},
+		{
+			name:        "unknown header label falls through to meta",
+			prefix:      "<html>",
+			contentType: "text/html; charset=klingon",
+			meta:        "utf-8",
+			want:        charsetInfo{Charset: "utf-8", Source: models.CharsetSourceMeta, Header: "klingon", Meta: "utf-8", Mismatch: true},
+		},
+		{
+			name:   "sniffed utf-8",
+			prefix: "<p>caf\xC3\xA9</p>",
+			want:   charsetInfo{Charset: "utf-8", Source: models.CharsetSourceSniffed},
+		},
+		{
+			name:   "sniffed utf-8 with a character cut off at the end",
+			prefix: "<p>caf\xC3",
+			want:   charsetInfo{Charset: "utf-8", Source: models.CharsetSourceSniffed},
+		},
+		{
+			name:   "sniffed legacy encoding",
+			prefix: "<p>caf\xE9 au lait</p>",
+			want:   charsetInfo{Charset: "windows-1252", Source: models.CharsetSourceSniffed},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enc, got := detectCharset([]byte(tt.prefix), tt.contentType, tt.meta)
+			if enc == nil {
+				t.Fatal("detectCharset returned no encoding")
+			}
+			if got != tt.want {
+				t.Errorf("detectCharset() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrescanMetaCharset(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix string
+		want   string
+	}{
+		{"none", "<html><head><title>x</title></head>", ""},
+		{"meta charset", `<html><head><meta charset="utf-8">`, "utf-8"},
+		{"unquoted and padded", `<meta charset= shift_jis >`, "shift_jis"},
+		{"http-equiv", `<meta http-equiv="Content-Type" content="text/html; charset=ISO-8859-1">`, "ISO-8859-1"},
+		{"http-equiv in any case", `<META HTTP-EQUIV="content-type" CONTENT="text/html;charset=euc-jp">`, "euc-jp"},
+		{"http-equiv without charset", `<meta http-equiv="Content-Type" content="text/html">`, ""},
+		{"other meta first", `<meta name="viewport" content="width=device-width"><meta charset="koi8-r">`, "koi8-r"},
+		{"self-closing", `<meta charset="utf-8"/>`, "utf-8"},
+		{"inside a comment", `<!-- <meta charset="big5"> --><meta charset="utf-8">`, "utf-8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := prescanMetaCharset([]byte(tt.prefix)); got != tt.want {
+				t.Errorf("prescanMetaCharset(%q) = %q, want %q", tt.prefix, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package crawler
+
+import "time"
+
+// Config holds the tunable limits used by the crawler
+type Config struct {
+	// LinkCheckConcurrency is the maximum number of links checked at once
+	LinkCheckConcurrency int
+	// LinkCheckPerHost is the maximum number of concurrent checks against a single host
+	LinkCheckPerHost int
+	// LinkCheckTimeout bounds each individual request made while checking a link
+	LinkCheckTimeout time.Duration
+	// LinkCheckBudget bounds the total time spent checking the links of one page
+	LinkCheckBudget time.Duration
+	// LinkCheckRetries is the number of extra attempts made for transient failures
+	LinkCheckRetries int
+	// LinkCheckBackoff is the initial delay between retries, doubled on each attempt
+	LinkCheckBackoff time.Duration
+	// LinkCheckMaxRetryAfter caps the delay honored from Retry-After headers
+	LinkCheckMaxRetryAfter time.Duration
+	// MaxBodySize is the most bytes of a page read and parsed; longer pages
+	// are analyzed up to the limit and flagged as truncated
+	MaxBodySize int64
+	// AssetFetchLimit is the most bytes read from an asset whose size is not
+	// declared by the server
+	AssetFetchLimit int64
+	// TechnologyRulesFile is the path of a JSON rule file used instead of the
+	// built-in technology fingerprints; empty uses the built-in rules
+	TechnologyRulesFile string
+	// SitemapCheckLimit is the most sitemap URLs requested to verify their
+	// status and canonical link
+	SitemapCheckLimit int
+	// RedirectChainLimit is the number of redirects a page may take before
+	// its chain is flagged as too long
+	RedirectChainLimit int
+}
+
+// DefaultConfig returns the crawler configuration used by NewCrawlerService
+func DefaultConfig() Config {
+	return Config{
+		LinkCheckConcurrency:   20,
+		LinkCheckPerHost:       4,
+		LinkCheckTimeout:       5 * time.Second,
+		LinkCheckBudget:        30 * time.Second,
+		LinkCheckRetries:       2,
+		LinkCheckBackoff:       500 * time.Millisecond,
+		LinkCheckMaxRetryAfter: 10 * time.Second,
+		MaxBodySize:            10 << 20,
+		AssetFetchLimit:        10 << 20,
+		SitemapCheckLimit:      100,
+		RedirectChainLimit:     3,
+	}
+}
@@ -2,9 +2,9 @@ package crawler
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"strings"
@@ -17,68 +17,219 @@ import (
 
 // CrawlerService handles website crawling and analysis
 type CrawlerService struct {
-	client *http.Client
+	client             *http.Client
+	pageClient         *http.Client
+	robotsClient       *http.Client
+	linkChecker        *linkChecker
+	maxBodySize        int64
+	assetFetchLimit    int64
+	technologies       []*technology
+	robots             *robotsCache
+	tlsVerifier        *tlsVerifier
+	sitemapCheckLimit  int
+	redirectChainLimit int
 }
 
 // NewCrawlerService creates a new crawler service
 func NewCrawlerService() *CrawlerService {
+	return NewCrawlerServiceWithConfig(DefaultConfig())
+}
+
+// NewCrawlerServiceWithConfig creates a new crawler service with custom limits
+func NewCrawlerServiceWithConfig(cfg Config) *CrawlerService {
+	client := &http.Client{Timeout: 30 * time.Second}
+
+	// Pages and robots.txt are fetched whatever their certificate, so a broken
+	// chain is inspected and reported rather than ending the crawl
+	verifier := newTLSVerifier()
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true, VerifyConnection: verifier.verifyConnection}
+	robotsClient := *client
+	robotsClient.Transport = transport
+
+	// Pages are fetched without following redirects so each hop can be recorded
+	pageClient := robotsClient
+	pageClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
 	return &CrawlerService{
-		client: &http.Client{Timeout: 30 * time.Second},
+		client:             client,
+		pageClient:         &pageClient,
+		linkChecker:        newLinkChecker(client, cfg),
+		maxBodySize:        cfg.MaxBodySize,
+		assetFetchLimit:    cfg.AssetFetchLimit,
+		technologies:       loadTechnologies(cfg.TechnologyRulesFile),
+		robots:             newRobotsCache(),
+		robotsClient:       &robotsClient,
+		tlsVerifier:        verifier,
+		sitemapCheckLimit:  cfg.SitemapCheckLimit,
+		redirectChainLimit: cfg.RedirectChainLimit,
 	}
 }
 
+// PageOptions holds per-URL settings applied to every page analyzed
+type PageOptions struct {
+	// Technologies are fingerprinting rules checked alongside the built-in ones
+	Technologies []TechnologyRule
+	// IgnoreRobots fetches pages robots.txt disallows, for sites the user owns
+	IgnoreRobots bool
+}
+
 // CrawlWebsite crawls a website and returns analysis results
 func (c *CrawlerService) CrawlWebsite(ctx context.Context, targetURL string) (*models.Analysis, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", targetURL, nil)
+	return c.CrawlWebsiteWithOptions(ctx, targetURL, PageOptions{})
+}
+
+// CrawlWebsiteWithOptions crawls a website with per-URL settings and returns analysis results
+func (c *CrawlerService) CrawlWebsiteWithOptions(ctx context.Context, targetURL string, opts PageOptions) (*models.Analysis, error) {
+	analysis, _, err := c.crawlPage(ctx, targetURL, opts, compileTechnologies(opts.Technologies))
+	return analysis, err
+}
+
+// crawlPage fetches and analyzes a single page, returning the analysis along
+// with the internal page links found on it. technologies are opts.Technologies
+// compiled by the caller.
+func (c *CrawlerService) crawlPage(ctx context.Context, targetURL string, opts PageOptions, technologies []*technology) (*models.Analysis, []string, error) {
+	pageURL, err := url.Parse(targetURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	robots, robotsErr := c.checkRobots(ctx, pageURL, opts.IgnoreRobots)
+	if robotsErr != nil && !opts.IgnoreRobots {
+		return nil, nil, robotsErr
+	}
+	// Only a robots.txt that was read can disallow the page
+	disallowed := robotsErr == nil && !robots.Allowed
+	if disallowed && !opts.IgnoreRobots {
+		// Disallowed pages are reported without being fetched
+		return &models.Analysis{RobotsDisallowed: true, Robots: c.robotsJSON(robots)}, nil, nil
 	}
 
-	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; WebsiteCrawler/1.0)")
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch URL: %w", err)
+	// Every request to a host is spaced out by its Crawl-delay, unless the
+	// URL is set to ignore robots.txt
+	var throttle requestThrottle
+	if !opts.IgnoreRobots {
+		throttle = c.waitCrawlDelay
+		if !throttle(ctx, pageURL) {
+			return nil, nil, ctx.Err()
+		}
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	fetched, err := c.fetchPage(ctx, targetURL, opts.IgnoreRobots)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, nil, err
 	}
 
-	doc, err := goquery.NewDocumentFromReader(strings.NewReader(string(body)))
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	analysis := &models.Analysis{RobotsDisallowed: disallowed, Robots: c.robotsJSON(robots)}
+	c.recordRedirects(analysis, fetched)
+	if fetched.DisallowedHop != nil {
+		// A redirect led to a page robots.txt keeps us out of
+		analysis.RobotsDisallowed = true
+		analysis.Robots = c.robotsJSON(*fetched.DisallowedHop)
+	}
+	if fetched.Response == nil {
+		// The redirects never settled on a page, so there is nothing to parse
+		return analysis, nil, nil
 	}
 
-	baseURL, _ := url.Parse(targetURL)
-	analysis := &models.Analysis{
-		HTMLVersion:  c.detectHTMLVersion(doc),
-		Title:        c.extractTitle(doc),
-		Headings:     c.countHeadings(doc),
-		HasLoginForm: c.detectLoginForm(doc),
+	resp := fetched.Response
+	defer resp.Body.Close()
+
+	if tlsInfo := c.inspectTLS(resp.TLS, fetched.FinalURL.Hostname()); tlsInfo != nil {
+		expiresAt := chainExpiry(tlsInfo)
+		analysis.CertExpiresAt = &expiresAt
+		analysis.CertDaysToExpiry = models.DaysUntil(analysis.CertExpiresAt)
+		analysis.TLSInfo = c.tlsInfoJSON(tlsInfo)
 	}
 
-	internalLinks, externalLinks, brokenLinks := c.analyzeLinks(doc, baseURL)
-	analysis.InternalLinks = internalLinks
-	analysis.ExternalLinks = externalLinks
-	analysis.InaccessibleLinks = len(brokenLinks)
+	securityAudit := c.auditSecurityHeaders(resp.Header, fetched.FinalURL.Scheme == "https")
+	analysis.SecurityGrade = securityAudit.Grade
+	analysis.SecurityHeaders = c.securityHeadersJSON(securityAudit)
 
-	if brokenLinksJSON, err := json.Marshal(brokenLinks); err == nil {
-		analysis.BrokenLinks = string(brokenLinksJSON)
+	limit := c.maxBodySize
+	if limit <= 0 {
+		limit = DefaultConfig().MaxBodySize
+	}
+	body := newBodyReader(resp.Body, limit)
+	analysis.DocumentType = documentType(resp.Header.Get("Content-Type"), body)
+	if analysis.DocumentType != models.DocumentHTML {
+		// PDFs, images and text get their own lightweight analysis
+		pageLinks, err := c.analyzeDocument(analysis, body, resp.Header.Get("Content-Type"), fetched.FinalURL)
+		if err != nil {
+			return nil, nil, err
+		}
+		c.recordResponse(analysis, fetched, int(body.limited.read), body.limited.doneAt())
+		analysis.BodyTruncated = body.limited.truncated
+		return analysis, pageLinks, nil
 	}
 
-	return analysis, nil
-}
+	// Everything after this point works on UTF-8, whatever the page was served in
+	doc, source, charset, err := parseHTML(body, resp.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, nil, err
+	}
+	c.recordResponse(analysis, fetched, int(body.limited.read), body.limited.doneAt())
+	analysis.BodyTruncated = body.limited.truncated
+	analysis.Charset = charset.Charset
+	analysis.CharsetSource = charset.Source
+	analysis.HeaderCharset = charset.Header
+	analysis.MetaCharset = charset.Meta
+	analysis.CharsetMismatch = charset.Mismatch
+
+	// Links are resolved against the page we landed on, not the one requested
+	baseURL := fetched.FinalURL
+	version := c.detectHTMLVersion(doc)
+	analysis.HTMLVersion = version.Version
+	analysis.HTMLVersionSource = version.Source
+	analysis.Doctype = version.Doctype
+	analysis.DocumentMode = version.Mode
+	analysis.Title = c.extractTitle(doc)
+	analysis.Headings = c.countHeadings(doc)
+	analysis.HeadingOutline = c.headingOutlineJSON(doc)
 
-func (c *CrawlerService) detectHTMLVersion(doc *goquery.Document) string {
-	if doc.Find("header, nav, main, section, article, aside, footer").Length() > 0 {
-		return "HTML5"
+	login := c.detectLogin(doc)
+	analysis.HasLoginForm = login.Confidence >= loginThreshold
+	analysis.LoginConfidence = login.Confidence
+	analysis.LoginDetection = c.loginDetectionJSON(login)
+
+	forms := c.inventoryForms(doc, baseURL)
+	analysis.InsecureForms = countInsecureForms(forms)
+	analysis.Forms = c.formsJSON(forms)
+
+	analysis.SEO = c.seoJSON(c.auditSEO(doc, resp.Header, baseURL))
+	analysis.StructuredData = c.structuredDataJSON(c.extractStructuredData(doc))
+
+	accessibility := c.auditAccessibility(doc)
+	analysis.AccessibilityIssues = accessibility.Total
+	analysis.Accessibility = c.accessibilityJSON(accessibility)
+
+	links := c.analyzeLinks(ctx, doc, baseURL, source, throttle)
+	analysis.InternalLinks = links.Internal
+	analysis.ExternalLinks = links.External
+	analysis.InaccessibleLinks = len(links.Broken)
+	analysis.UncheckedLinks = links.Unchecked
+
+	if brokenLinksJSON, err := json.Marshal(links.Broken); err == nil {
+		analysis.BrokenLinks = string(brokenLinksJSON)
 	}
-	if doc.Find("html[xmlns]").Length() > 0 {
-		return "XHTML"
+
+	if resourceCountsJSON, err := json.Marshal(links.ResourceCounts); err == nil {
+		analysis.ResourceCounts = string(resourceCountsJSON)
 	}
-	return "HTML4"
+
+	assets := c.inventoryAssets(ctx, doc, baseURL, links, analysis.BodySize, throttle)
+	analysis.PageWeight = assets.TotalBytes
+	analysis.RequestCount = assets.Requests
+	analysis.Assets = c.assetsJSON(assets)
+
+	analysis.Technologies = c.detectTechnologies(resp.Header, resp.Cookies(), doc, links.Refs, source, technologies)
+
+	mixed := c.detectMixedContent(baseURL, links.Refs, forms, securityAudit.CSP)
+	analysis.MixedContentCount = len(mixed.Items)
+	analysis.MixedContent = c.mixedContentJSON(mixed)
+
+	return analysis, c.extractPageLinks(doc, baseURL), nil
 }
 
 func (c *CrawlerService) extractTitle(doc *goquery.Document) string {
@@ -111,82 +262,85 @@ func (c *CrawlerService) countHeadings(doc *goquery.Document) string {
 	return "{}"
 }
 
-func (c *CrawlerService) detectLoginForm(doc *goquery.Document) bool {
-	loginIndicators := []string{
-		"input[type='password']",
-		"form[action*='login']",
-		"form[action*='signin']",
-		".login-form",
-		"#login-form",
-	}
-	for _, selector := range loginIndicators {
-		if doc.Find(selector).Length() > 0 {
-			return true
-		}
-	}
-	return false
+// linkAnalysis summarizes the links and subresources referenced by a page
+type linkAnalysis struct {
+	Internal       int
+	External       int
+	Broken         []models.BrokenLink
+	Unchecked      int
+	ResourceCounts map[string]models.ResourceCount
+	Refs           []resourceRef
+	Checked        map[string]linkCheckResult
 }
 
-func (c *CrawlerService) analyzeLinks(doc *goquery.Document, baseURL *url.URL) (int, int, []models.BrokenLink) {
-	var internalLinks, externalLinks int
-	var brokenLinks []models.BrokenLink
+func (c *CrawlerService) analyzeLinks(ctx context.Context, doc *goquery.Document, baseURL *url.URL, source string, throttle requestThrottle) linkAnalysis {
+	result := linkAnalysis{ResourceCounts: make(map[string]models.ResourceCount)}
+	var toCheck []*url.URL
+	resourceTypes := make(map[string]string)
+	locations := make(map[string][]models.LinkLocation)
 
-	doc.Find("a[href]").Each(func(i int, s *goquery.Selection) {
-		href, exists := s.Attr("href")
-		if !exists {
-			return
+	refs := collectReferences(doc, baseURL, newSourceLocator(source))
+	result.Refs = refs
+	for _, ref := range refs {
+		if ref.Type == models.ResourceLink {
+			if ref.URL.Hostname() == baseURL.Hostname() {
+				result.Internal++
+			} else {
+				result.External++
+			}
 		}
 
-		linkURL, err := url.Parse(href)
-		if err != nil {
-			return
+		// Each distinct target is only checked once
+		key := ref.URL.String()
+		if _, seen := locations[key]; !seen {
+			toCheck = append(toCheck, ref.URL)
+			resourceTypes[key] = ref.Type
 		}
+		locations[key] = append(locations[key], ref.Location)
+	}
 
-		if !linkURL.IsAbs() {
-			linkURL = baseURL.ResolveReference(linkURL)
-		}
-
-		if linkURL.Scheme != "http" && linkURL.Scheme != "https" {
-			return
-		}
-
-		if linkURL.Hostname() == baseURL.Hostname() {
-			internalLinks++
-		} else {
-			externalLinks++
-		}
-
-		// Check link accessibility with timeout
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-
-		req, err := http.NewRequestWithContext(ctx, "GET", linkURL.String(), nil)
-		if err != nil {
-			brokenLinks = append(brokenLinks, models.BrokenLink{
-				URL:   linkURL.String(),
-				Error: err.Error(),
+	checked := c.linkChecker.checkAll(ctx, toCheck, throttle)
+	result.Checked = checked
+	broken := make(map[string]bool)
+	for _, linkURL := range toCheck {
+		key := linkURL.String()
+		check := checked[key]
+		switch {
+		case !check.Checked:
+			result.Unchecked++
+		case check.Err != nil:
+			broken[key] = true
+			result.Broken = append(result.Broken, models.BrokenLink{
+				URL:          key,
+				ResourceType: resourceTypes[key],
+				Error:        check.Err.Error(),
+				Category:     classifyFailure(0, check.Err),
+				Attempts:     check.Attempts,
+				CheckedAt:    check.CheckedAt,
+				Locations:    locations[key],
 			})
-			return
-		}
-
-		req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; WebsiteCrawler/1.0)")
-		resp, err := c.client.Do(req)
-		if err != nil {
-			brokenLinks = append(brokenLinks, models.BrokenLink{
-				URL:   linkURL.String(),
-				Error: err.Error(),
+		case check.StatusCode >= 400:
+			broken[key] = true
+			result.Broken = append(result.Broken, models.BrokenLink{
+				URL:          key,
+				ResourceType: resourceTypes[key],
+				StatusCode:   check.StatusCode,
+				Category:     classifyFailure(check.StatusCode, nil),
+				Attempts:     check.Attempts,
+				CheckedAt:    check.CheckedAt,
+				Locations:    locations[key],
 			})
-			return
 		}
-		defer resp.Body.Close()
+	}
 
-		if resp.StatusCode >= 400 {
-			brokenLinks = append(brokenLinks, models.BrokenLink{
-				URL:        linkURL.String(),
-				StatusCode: resp.StatusCode,
-			})
+	for _, ref := range refs {
+		count := result.ResourceCounts[ref.Type]
+		count.Total++
+		if broken[ref.URL.String()] {
+			count.Broken++
 		}
-	})
+		result.ResourceCounts[ref.Type] = count
+	}
 
-	return internalLinks, externalLinks, brokenLinks
+	return result
 }
@@ -0,0 +1,239 @@
+package crawler
+
+import (
+	"strings"
+
+	"website-crawler/internal/models"
+
+	"github.com/PuerkitoBio/goquery"
+	nethtml "golang.org/x/net/html"
+)
+
+// htmlVersion describes the HTML version a page declares and the mode
+// browsers will render it in
+type htmlVersion struct {
+	Version string
+	Source  string
+	Doctype string
+	Mode    string
+}
+
+// doctypeVersions maps public identifier fragments to version names. More
+// specific fragments come first since matching stops at the first hit.
+var doctypeVersions = []struct {
+	fragment string
+	version  string
+}{
+	{"XHTML 1.0 STRICT", "XHTML 1.0 Strict"},
+	{"XHTML 1.0 TRANSITIONAL", "XHTML 1.0 Transitional"},
+	{"XHTML 1.0 FRAMESET", "XHTML 1.0 Frameset"},
+	{"XHTML 1.1", "XHTML 1.1"},
+	{"XHTML BASIC", "XHTML Basic"},
+	{"XHTML+RDFA", "XHTML+RDFa"},
+	{"HTML 4.01 TRANSITIONAL", "HTML 4.01 Transitional"},
+	{"HTML 4.01 FRAMESET", "HTML 4.01 Frameset"},
+	{"HTML 4.01", "HTML 4.01 Strict"},
+	{"HTML 4.0 TRANSITIONAL", "HTML 4.0 Transitional"},
+	{"HTML 4.0 FRAMESET", "HTML 4.0 Frameset"},
+	{"HTML 4.0", "HTML 4.0 Strict"},
+	{"HTML 3.2", "HTML 3.2"},
+	{"HTML 2.0", "HTML 2.0"},
+}
+
+// Public identifiers that trigger quirks mode, from the WHATWG HTML
+// specification's "initial" insertion mode
+var (
+	quirksPublicIDs = []string{
+		"-//W3O//DTD W3 HTML STRICT 3.0//EN//",
+		"-/W3C/DTD HTML 4.0 TRANSITIONAL/EN",
+		"HTML",
+	}
+	quirksPublicIDPrefixes = []string{
+		"+//SILMARIL//DTD HTML PRO V0R11 19970101//",
+		"-//AS//DTD HTML 3.0 ASWEDIT + EXTENSIONS//",
+		"-//ADVASOFT LTD//DTD HTML 3.0 ASWEDIT + EXTENSIONS//",
+		"-//IETF//DTD HTML 2.0 LEVEL 1//",
+		"-//IETF//DTD HTML 2.0 LEVEL 2//",
+		"-//IETF//DTD HTML 2.0 STRICT LEVEL 1//",
+		"-//IETF//DTD HTML 2.0 STRICT LEVEL 2//",
+		"-//IETF//DTD HTML 2.0 STRICT//",
+		"-//IETF//DTD HTML 2.0//",
+		"-//IETF//DTD HTML 2.1E//",
+		"-//IETF//DTD HTML 3.0//",
+		"-//IETF//DTD HTML 3.2 FINAL//",
+		"-//IETF//DTD HTML 3.2//",
+		"-//IETF//DTD HTML 3//",
+		"-//IETF//DTD HTML LEVEL 0//",
+		"-//IETF//DTD HTML LEVEL 1//",
+		"-//IETF//DTD HTML LEVEL 2//",
+		"-//IETF//DTD HTML LEVEL 3//",
+		"-//IETF//DTD HTML STRICT LEVEL 0//",
+		"-//IETF//DTD HTML STRICT LEVEL 1//",
+		"-//IETF//DTD HTML STRICT LEVEL 2//",
+		"-//IETF//DTD HTML STRICT LEVEL 3//",
+		"-//IETF//DTD HTML STRICT//",
+		"-//IETF//DTD HTML//",
+		"-//METRIUS//DTD METRIUS PRESENTATIONAL//",
+		"-//MICROSOFT//DTD INTERNET EXPLORER 2.0 HTML STRICT//",
+		"-//MICROSOFT//DTD INTERNET EXPLORER 2.0 HTML//",
+		"-//MICROSOFT//DTD INTERNET EXPLORER 2.0 TABLES//",
+		"-//MICROSOFT//DTD INTERNET EXPLORER 3.0 HTML STRICT//",
+		"-//MICROSOFT//DTD INTERNET EXPLORER 3.0 HTML//",
+		"-//MICROSOFT//DTD INTERNET EXPLORER 3.0 TABLES//",
+		"-//NETSCAPE COMM. CORP.//DTD HTML//",
+		"-//NETSCAPE COMM. CORP.//DTD STRICT HTML//",
+		"-//O'REILLY AND ASSOCIATES//DTD HTML 2.0//",
+		"-//O'REILLY AND ASSOCIATES//DTD HTML EXTENDED 1.0//",
+		"-//O'REILLY AND ASSOCIATES//DTD HTML EXTENDED RELAXED 1.0//",
+		"-//SQ//DTD HTML 2.0 HOTMETAL + EXTENSIONS//",
+		"-//SOFTQUAD SOFTWARE//DTD HOTMETAL PRO 6.0::19990601::EXTENSIONS TO HTML 4.0//",
+		"-//SOFTQUAD//DTD HOTMETAL PRO 4.0::19971010::EXTENSIONS TO HTML 4.0//",
+		"-//SPYGLASS//DTD HTML 2.0 EXTENDED//",
+		"-//SUN MICROSYSTEMS CORP.//DTD HOTJAVA HTML//",
+		"-//SUN MICROSYSTEMS CORP.//DTD HOTJAVA STRICT HTML//",
+		"-//W3C//DTD HTML 3 1995-03-24//",
+		"-//W3C//DTD HTML 3.2 DRAFT//",
+		"-//W3C//DTD HTML 3.2 FINAL//",
+		"-//W3C//DTD HTML 3.2//",
+		"-//W3C//DTD HTML 3.2S DRAFT//",
+		"-//W3C//DTD HTML 4.0 FRAMESET//",
+		"-//W3C//DTD HTML 4.0 TRANSITIONAL//",
+		"-//W3C//DTD HTML EXPERIMENTAL 19960712//",
+		"-//W3C//DTD HTML EXPERIMENTAL 970421//",
+		"-//W3C//DTD W3 HTML//",
+		"-//W3O//DTD W3 HTML 3.0//",
+		"-//WEBTECHS//DTD MOZILLA HTML 2.0//",
+		"-//WEBTECHS//DTD MOZILLA HTML//",
+	}
+	// These are quirks without a system identifier and limited quirks with one
+	transitionalPublicIDPrefixes = []string{
+		"-//W3C//DTD HTML 4.01 FRAMESET//",
+		"-//W3C//DTD HTML 4.01 TRANSITIONAL//",
+	}
+	limitedQuirksPublicIDPrefixes = []string{
+		"-//W3C//DTD XHTML 1.0 FRAMESET//",
+		"-//W3C//DTD XHTML 1.0 TRANSITIONAL//",
+	}
+)
+
+const quirksSystemID = "HTTP://WWW.IBM.COM/DATA/DTD/V11/IBMXHTML1-TRANSITIONAL.DTD"
+
+func (c *CrawlerService) detectHTMLVersion(doc *goquery.Document) htmlVersion {
+	doctype := findDoctype(doc)
+	if doctype == nil {
+		return htmlVersion{
+			Version: guessHTMLVersion(doc),
+			Source:  models.VersionSourceHeuristic,
+			Mode:    models.DocumentModeQuirks,
+		}
+	}
+
+	publicID, hasPublicID := "", false
+	systemID, hasSystemID := "", false
+	for _, attr := range doctype.Attr {
+		switch attr.Key {
+		case "public":
+			publicID, hasPublicID = attr.Val, true
+		case "system":
+			systemID, hasSystemID = attr.Val, true
+		}
+	}
+
+	return htmlVersion{
+		Version: doctypeVersion(doctype.Data, publicID, systemID, hasPublicID, hasSystemID),
+		Source:  models.VersionSourceDoctype,
+		Doctype: formatDoctype(doctype.Data, publicID, systemID, hasPublicID, hasSystemID),
+		Mode:    documentMode(doctype.Data, publicID, systemID, hasSystemID),
+	}
+}
+
+func findDoctype(doc *goquery.Document) *nethtml.Node {
+	for _, root := range doc.Nodes {
+		for node := root.FirstChild; node != nil; node = node.NextSibling {
+			if node.Type == nethtml.DoctypeNode {
+				return node
+			}
+		}
+	}
+	return nil
+}
+
+func doctypeVersion(name, publicID, systemID string, hasPublicID, hasSystemID bool) string {
+	if !strings.EqualFold(name, "html") {
+		return "Unknown"
+	}
+
+	if !hasPublicID && (!hasSystemID || strings.EqualFold(systemID, "about:legacy-compat")) {
+		return "HTML5"
+	}
+
+	upper := strings.ToUpper(publicID)
+	for _, known := range doctypeVersions {
+		if strings.Contains(upper, known.fragment) {
+			return known.version
+		}
+	}
+	return "Unknown"
+}
+
+// documentMode applies the WHATWG rules for choosing quirks, limited-quirks
+// or no-quirks mode from a DOCTYPE token
+func documentMode(name, publicID, systemID string, hasSystemID bool) string {
+	publicID = strings.ToUpper(publicID)
+	systemID = strings.ToUpper(systemID)
+
+	if !strings.EqualFold(name, "html") || systemID == quirksSystemID {
+		return models.DocumentModeQuirks
+	}
+	for _, id := range quirksPublicIDs {
+		if publicID == id {
+			return models.DocumentModeQuirks
+		}
+	}
+	for _, prefix := range quirksPublicIDPrefixes {
+		if strings.HasPrefix(publicID, prefix) {
+			return models.DocumentModeQuirks
+		}
+	}
+	for _, prefix := range transitionalPublicIDPrefixes {
+		if strings.HasPrefix(publicID, prefix) {
+			if !hasSystemID {
+				return models.DocumentModeQuirks
+			}
+			return models.DocumentModeLimitedQuirks
+		}
+	}
+	for _, prefix := range limitedQuirksPublicIDPrefixes {
+		if strings.HasPrefix(publicID, prefix) {
+			return models.DocumentModeLimitedQuirks
+		}
+	}
+	return models.DocumentModeStandards
+}
+
+func formatDoctype(name, publicID, systemID string, hasPublicID, hasSystemID bool) string {
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE ")
+	b.WriteString(name)
+	if hasPublicID {
+		b.WriteString(` PUBLIC "` + publicID + `"`)
+		if hasSystemID {
+			b.WriteString(` "` + systemID + `"`)
+		}
+	} else if hasSystemID {
+		b.WriteString(` SYSTEM "` + systemID + `"`)
+	}
+	b.WriteString(">")
+	return b.String()
+}
+
+// guessHTMLVersion infers a version from markup when the page has no DOCTYPE
+func guessHTMLVersion(doc *goquery.Document) string {
+	if doc.Find("header, nav, main, section, article, aside, footer").Length() > 0 {
+		return "HTML5"
+	}
+	if doc.Find("html[xmlns]").Length() > 0 {
+		return "XHTML"
+	}
+	return "HTML4"
+}
@@ -0,0 +1,71 @@
+package crawler
+
+import (
+	"strings"
+	"testing"
+
+	"website-crawler/internal/models"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestDetectHTMLVersionMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		doctype string
+		version string
+		mode    string
+	}{
+		{"html5", `<!DOCTYPE html>`, "HTML5", models.DocumentModeStandards},
+		{"html5 legacy compat", `<!DOCTYPE html SYSTEM "about:legacy-compat">`, "HTML5", models.DocumentModeStandards},
+		{"missing doctype", ``, "", models.DocumentModeQuirks},
+		{"not html", `<!DOCTYPE svg>`, "Unknown", models.DocumentModeQuirks},
+		{
+			"html 4.01 strict",
+			`<!DOCTYPE HTML PUBLIC "-//W3C//DTD HTML 4.01//EN" "http://www.w3.org/TR/html4/strict.dtd">`,
+			"HTML 4.01 Strict", models.DocumentModeStandards,
+		},
+		{
+			"html 4.01 transitional with system id",
+			`<!DOCTYPE HTML PUBLIC "-//W3C//DTD HTML 4.01 Transitional//EN" "http://www.w3.org/TR/html4/loose.dtd">`,
+			"HTML 4.01 Transitional", models.DocumentModeLimitedQuirks,
+		},
+		{
+			"html 4.01 transitional without system id",
+			`<!DOCTYPE HTML PUBLIC "-//W3C//DTD HTML 4.01 Transitional//EN">`,
+			"HTML 4.01 Transitional", models.DocumentModeQuirks,
+		},
+		{
+			"xhtml 1.0 transitional",
+			`<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Transitional//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-transitional.dtd">`,
+			"XHTML 1.0 Transitional", models.DocumentModeLimitedQuirks,
+		},
+		{
+			"html 3.2",
+			`<!DOCTYPE HTML PUBLIC "-//W3C//DTD HTML 3.2 Final//EN">`,
+			"HTML 3.2", models.DocumentModeQuirks,
+		},
+		{
+			"ibm system id",
+			`<!DOCTYPE html SYSTEM "http://www.ibm.com/data/dtd/v11/ibmxhtml1-transitional.dtd">`,
+			"Unknown", models.DocumentModeQuirks,
+		},
+	}
+
+	c := NewCrawlerService()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.doctype + "<html><head><title>x</title></head><body></body></html>"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := c.detectHTMLVersion(doc)
+			if got.Mode != tt.mode {
+				t.Errorf("mode = %q, want %q", got.Mode, tt.mode)
+			}
+			if tt.version != "" && got.Version != tt.version {
+				t.Errorf("version = %q, want %q", got.Version, tt.version)
+			}
+		})
+	}
+}
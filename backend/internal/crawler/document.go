@@ -0,0 +1,441 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"website-crawler/internal/models"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+var (
+	pdfVersion    = regexp.MustCompile(`^%PDF-(\d\.\d)`)
+	pdfPage       = regexp.MustCompile(`/Type\s*/Page\b`)
+	pdfPagesCount = regexp.MustCompile(`/Type\s*/Pages\b[^<>]{0,256}?/Count\s+(\d+)|/Count\s+(\d+)[^<>]{0,256}?/Type\s*/Pages\b`)
+	pdfInfoRef    = regexp.MustCompile(`/Info\s+(\d+)\s+(\d+)\s+R`)
+	pdfEncrypt    = regexp.MustCompile(`/Encrypt\s*(\d+\s+\d+\s+R|<<)`)
+	pdfStream     = regexp.MustCompile(`>>\s*stream\r?\n`)
+	pdfURI        = regexp.MustCompile(`/URI\s*[(<]`)
+	pdfObjHeader  = regexp.MustCompile(`(?:^|\s)(\d+)\s+(\d+)\s+obj\b`)
+)
+
+// pdfInflateLimit bounds the bytes decompressed from all of a PDF's object
+// streams together, so a document packed with many streams cannot get past it
+const pdfInflateLimit = 10 << 20
+
+// analyzeDocument reads a PDF, image or text response and records what it
+// holds on the analysis, returning the internal links it contains. Other
+// documents are left unread.
+func (c *CrawlerService) analyzeDocument(analysis *models.Analysis, body *bodyReader, contentType string, baseURL *url.URL) ([]string, error) {
+	switch analysis.DocumentType {
+	case models.DocumentPDF, models.DocumentImage, models.DocumentText:
+	default:
+		// Nothing to analyze, but reading the body still gives its size and truncation
+		if _, err := io.Copy(io.Discard, body); err != nil {
+			return nil, fmt.Errorf("failed to read response body: %w", err)
+		}
+		return nil, nil
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	document := models.DocumentInfo{Type: analysis.DocumentType, Size: int64(len(data))}
+	var pageLinks []string
+	switch analysis.DocumentType {
+	case models.DocumentPDF:
+		document.PDF = analyzePDF(data)
+		analysis.Title = document.PDF.Title
+
+		seen := map[string]bool{}
+		for _, link := range document.PDF.Links {
+			linkURL, ok := resolveReference(baseURL, link)
+			if !ok {
+				continue
+			}
+			if linkURL.Hostname() != baseURL.Hostname() {
+				analysis.ExternalLinks++
+				continue
+			}
+			analysis.InternalLinks++
+			if normalized := normalizePageURL(linkURL); !seen[normalized] {
+				seen[normalized] = true
+				pageLinks = append(pageLinks, normalized)
+			}
+		}
+	case models.DocumentImage:
+		document.Image = analyzeImage(data)
+	case models.DocumentText:
+		text, charset := analyzeText(data, contentType)
+		document.Text = text
+		analysis.Charset = charset.Charset
+		analysis.CharsetSource = charset.Source
+		analysis.HeaderCharset = charset.Header
+		analysis.CharsetMismatch = charset.Mismatch
+	}
+
+	analysis.Document = c.documentJSON(document)
+	return pageLinks, nil
+}
+
+func (c *CrawlerService) documentJSON(document models.DocumentInfo) string {
+	if jsonData, err := json.Marshal(document); err == nil {
+		return string(jsonData)
+	}
+	return "{}"
+}
+
+// analyzePDF reads the version, page count, document information and link
+// annotations of a PDF. Object streams are decompressed so PDFs written with
+// compressed cross-reference tables are read too.
+func analyzePDF(data []byte) *models.PDFInfo {
+	info := &models.PDFInfo{Links: []string{}}
+	if match := pdfVersion.FindSubmatch(data); match != nil {
+		info.Version = string(match[1])
+	}
+	info.Encrypted = pdfEncrypt.Match(data)
+
+	// Objects packed into compressed object streams are searched as well
+	content := data
+	if !info.Encrypted {
+		if streams := pdfObjectStreams(data); len(streams) > 0 {
+			content = append(bytes.Clone(data), streams...)
+		}
+	}
+
+	// The root of the page tree counts every page, so the largest count wins
+	for _, match := range pdfPagesCount.FindAllSubmatch(content, -1) {
+		count := match[1]
+		if count == nil {
+			count = match[2]
+		}
+		if n, err := strconv.Atoi(string(count)); err == nil && n > info.Pages {
+			info.Pages = n
+		}
+	}
+	if info.Pages == 0 {
+		info.Pages = len(pdfPage.FindAllIndex(content, -1))
+	}
+
+	// Strings are unreadable without the key, so encrypted PDFs stop here
+	if info.Encrypted {
+		return info
+	}
+
+	if match := pdfInfoRef.FindSubmatch(data); match != nil {
+		if object := pdfObject(data, string(match[1]), string(match[2])); object != nil {
+			info.Title = pdfDictString(object, "/Title")
+			info.Author = pdfDictString(object, "/Author")
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, loc := range pdfURI.FindAllIndex(content, -1) {
+		uri, ok := pdfString(content[loc[1]-1:])
+		uri = strings.TrimSpace(uri)
+		if ok && uri != "" && !seen[uri] {
+			seen[uri] = true
+			info.Links = append(info.Links, uri)
+		}
+	}
+	return info
+}
+
+// pdfObjectStreams returns the decompressed contents of a PDF's object streams
+func pdfObjectStreams(data []byte) []byte {
+	var contents []byte
+	budget := int64(pdfInflateLimit)
+	for _, loc := range pdfStream.FindAllIndex(data, -1) {
+		if budget <= 0 {
+			break
+		}
+
+		// The stream's dictionary starts after the "obj" keyword that opens the object
+		dictStart := max(0, loc[0]-1024)
+		dict := data[dictStart:loc[0]]
+		if i := bytes.LastIndex(dict, []byte("obj")); i >= 0 {
+			dict = dict[i:]
+		}
+		if !bytes.Contains(dict, []byte("/ObjStm")) || !bytes.Contains(dict, []byte("/FlateDecode")) {
+			continue
+		}
+
+		end := bytes.Index(data[loc[1]:], []byte("endstream"))
+		if end < 0 {
+			continue
+		}
+		reader, err := zlib.NewReader(bytes.NewReader(data[loc[1] : loc[1]+end]))
+		if err != nil {
+			continue
+		}
+		// A truncated stream still yields the objects inflated before the cut
+		inflated, _ := io.ReadAll(io.LimitReader(reader, budget))
+		reader.Close()
+		budget -= int64(len(inflated))
+		contents = append(contents, '\n')
+		contents = append(contents, inflated...)
+	}
+	return contents
+}
+
+// pdfObject returns the body of an indirect object, up to its endobj keyword
+func pdfObject(data []byte, number, generation string) []byte {
+	for _, loc := range pdfObjHeader.FindAllSubmatchIndex(data, -1) {
+		if string(data[loc[2]:loc[3]]) != number || string(data[loc[4]:loc[5]]) != generation {
+			continue
+		}
+		object := data[loc[1]:]
+		if end := bytes.Index(object, []byte("endobj")); end >= 0 {
+			object = object[:end]
+		}
+		return object
+	}
+	return nil
+}
+
+// pdfDictString returns the string stored under key in a PDF dictionary
+func pdfDictString(dict []byte, key string) string {
+	i := bytes.Index(dict, []byte(key))
+	if i < 0 {
+		return ""
+	}
+	value := bytes.TrimLeft(dict[i+len(key):], " \t\r\n")
+	s, _ := pdfString(value)
+	return strings.TrimSpace(s)
+}
+
+// pdfString decodes the literal "(...)" or hex "<...>" string at the start of
+// data. Strings starting with a UTF-16 byte order mark are decoded as such;
+// others are PDFDocEncoding, which is read as Latin-1.
+func pdfString(data []byte) (string, bool) {
+	if len(data) == 0 {
+		return "", false
+	}
+
+	var raw []byte
+	switch data[0] {
+	case '(':
+		depth := 0
+		for i := 1; i < len(data); i++ {
+			ch := data[i]
+			switch ch {
+			case '\\':
+				i++
+				if i >= len(data) {
+					return "", false
+				}
+				switch esc := data[i]; esc {
+				case 'n':
+					raw = append(raw, '\n')
+				case 'r':
+					raw = append(raw, '\r')
+				case 't':
+					raw = append(raw, '\t')
+				case 'b':
+					raw = append(raw, '\b')
+				case 'f':
+					raw = append(raw, '\f')
+				case '\r', '\n':
+					// A backslash at the end of a line continues the string
+				default:
+					if esc >= '0' && esc <= '7' {
+						n := 0
+						j := i
+						for ; j < len(data) && j < i+3 && data[j] >= '0' && data[j] <= '7'; j++ {
+							n = n*8 + int(data[j]-'0')
+						}
+						raw = append(raw, byte(n))
+						i = j - 1
+					} else {
+						raw = append(raw, esc)
+					}
+				}
+				continue
+			case '(':
+				depth++
+			case ')':
+				if depth == 0 {
+					return decodePDFText(raw), true
+				}
+				depth--
+			}
+			raw = append(raw, ch)
+		}
+		return "", false
+	case '<':
+		end := bytes.IndexByte(data, '>')
+		if end < 0 {
+			return "", false
+		}
+		hex := make([]byte, 0, end)
+		for _, ch := range data[1:end] {
+			if strings.IndexByte(" \t\r\n", ch) < 0 {
+				hex = append(hex, ch)
+			}
+		}
+		if len(hex)%2 == 1 {
+			hex = append(hex, '0')
+		}
+		for i := 0; i < len(hex); i += 2 {
+			n, err := strconv.ParseUint(string(hex[i:i+2]), 16, 8)
+			if err != nil {
+				return "", false
+			}
+			raw = append(raw, byte(n))
+		}
+		return decodePDFText(raw), true
+	}
+	return "", false
+}
+
+func decodePDFText(raw []byte) string {
+	if len(raw) >= 2 && raw[0] == 0xFE && raw[1] == 0xFF {
+		units := make([]uint16, 0, len(raw)/2)
+		for i := 2; i+1 < len(raw); i += 2 {
+			units = append(units, binary.BigEndian.Uint16(raw[i:]))
+		}
+		return string(utf16.Decode(units))
+	}
+	if bytes.HasPrefix(raw, []byte("\xEF\xBB\xBF")) && utf8.Valid(raw[3:]) {
+		return string(raw[3:])
+	}
+	runes := make([]rune, len(raw))
+	for i, b := range raw {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+// analyzeImage reads the format and dimensions of an image, or returns nil
+// when the format is not recognised
+func analyzeImage(data []byte) *models.ImageInfo {
+	if config, format, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		return &models.ImageInfo{Format: format, Width: config.Width, Height: config.Height}
+	}
+	if width, height, ok := webpSize(data); ok {
+		return &models.ImageInfo{Format: "webp", Width: width, Height: height}
+	}
+	if width, height, ok := svgSize(data); ok {
+		return &models.ImageInfo{Format: "svg", Width: width, Height: height}
+	}
+	return nil
+}
+
+// webpSize reads the canvas size from the header of a lossy, lossless or
+// extended WebP image
+func webpSize(data []byte) (int, int, bool) {
+	if len(data) < 30 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return 0, 0, false
+	}
+	switch string(data[12:16]) {
+	case "VP8 ":
+		width := int(binary.LittleEndian.Uint16(data[26:28]) & 0x3fff)
+		height := int(binary.LittleEndian.Uint16(data[28:30]) & 0x3fff)
+		return width, height, true
+	case "VP8L":
+		bits := binary.LittleEndian.Uint32(data[21:25])
+		return int(bits&0x3fff) + 1, int(bits>>14&0x3fff) + 1, true
+	case "VP8X":
+		width := int(data[24]) | int(data[25])<<8 | int(data[26])<<16
+		height := int(data[27]) | int(data[28])<<8 | int(data[29])<<16
+		return width + 1, height + 1, true
+	}
+	return 0, 0, false
+}
+
+// svgSize reads the size of an SVG image from its width and height
+// attributes, falling back to its viewBox
+func svgSize(data []byte) (int, int, bool) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return 0, 0, false
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if start.Name.Local != "svg" {
+			return 0, 0, false
+		}
+
+		var width, height float64
+		var viewBox []string
+		for _, attr := range start.Attr {
+			switch attr.Name.Local {
+			case "width":
+				width = svgLength(attr.Value)
+			case "height":
+				height = svgLength(attr.Value)
+			case "viewBox":
+				viewBox = strings.Fields(strings.ReplaceAll(attr.Value, ",", " "))
+			}
+		}
+		if (width == 0 || height == 0) && len(viewBox) == 4 {
+			width, _ = strconv.ParseFloat(viewBox[2], 64)
+			height, _ = strconv.ParseFloat(viewBox[3], 64)
+		}
+		return int(width), int(height), width > 0 && height > 0
+	}
+}
+
+// svgLength parses an absolute SVG length in pixels; relative lengths such as
+// percentages give 0
+func svgLength(value string) float64 {
+	value = strings.TrimSuffix(strings.TrimSpace(value), "px")
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// analyzeText measures a text document after decoding it to UTF-8
+func analyzeText(data []byte, contentType string) (*models.TextInfo, charsetInfo) {
+	// Text documents have no meta tags to declare a charset
+	enc, charset := detectCharset(data[:min(len(data), charsetPrescanBytes)], contentType, "")
+
+	if charset.Charset == "utf-8" && !utf8.Valid(bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF"))) {
+		if charset.Source == models.CharsetSourceSniffed {
+			// The start looked like UTF-8 but the rest is not
+			enc, _ = htmlindex.Get("windows-1252")
+			charset.Charset = "windows-1252"
+		} else {
+			charset.Mismatch = true
+		}
+	}
+
+	decoded, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		decoded = data
+	}
+	text := &models.TextInfo{
+		Length:  utf8.RuneCount(decoded),
+		Lines:   bytes.Count(decoded, []byte("\n")),
+		Charset: charset.Charset,
+	}
+	if len(decoded) > 0 && decoded[len(decoded)-1] != '\n' {
+		text.Lines++
+	}
+	return text, charset
+}
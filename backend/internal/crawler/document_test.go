@@ -0,0 +1,220 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/png"
+	"reflect"
+	"strings"
+	"testing"
+
+	"website-crawler/internal/models"
+)
+
+func TestPDFString(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+		ok   bool
+	}{
+		{"literal", "(Hello) rest", "Hello", true},
+		{"balanced parentheses", "(a (b) c)", "a (b) c", true},
+		{"escapes", `(line\nbreak \(paren\) back\\slash)`, "line\nbreak (paren) back\\slash", true},
+		{"octal escapes", `(\101\102C)`, "ABC", true},
+		{"line continuation", "(split \\\nline)", "split line", true},
+		{"latin-1", "(caf\xe9)", "café", true},
+		{"utf-16 literal", "(\xfe\xff\x00H\x00i)", "Hi", true},
+		{"hex", "<48656C6C6F>", "Hello", true},
+		{"hex with whitespace", "<48 65\n6C 6C 6F>", "Hello", true},
+		{"odd hex is padded", "<414>", "A@", true},
+		{"utf-16 hex", "<FEFF00480069>", "Hi", true},
+		{"unterminated literal", "(never closed", "", false},
+		{"unterminated hex", "<4142", "", false},
+		{"invalid hex", "<zz>", "", false},
+		{"not a string", "/Name", "", false},
+		{"empty", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := pdfString([]byte(tt.data))
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("pdfString(%q) = %q, %v, want %q, %v", tt.data, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestPDFObject(t *testing.T) {
+	data := []byte("%PDF-1.4\n1 0 obj\n<< /Title (One) >>\nendobj\n11 0 obj\n<< /Title (Eleven) >>\nendobj\n2 1 obj << /Title (Two) >> endobj\n")
+
+	tests := []struct {
+		number     string
+		generation string
+		want       string
+	}{
+		{"1", "0", "One"},
+		{"11", "0", "Eleven"},
+		{"2", "1", "Two"},
+		{"2", "0", ""},
+		{"3", "0", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.number+" "+tt.generation, func(t *testing.T) {
+			object := pdfObject(data, tt.number, tt.generation)
+			if tt.want == "" {
+				if object != nil {
+					t.Errorf("pdfObject(%s %s) = %q, want nil", tt.number, tt.generation, object)
+				}
+				return
+			}
+			if got := pdfDictString(object, "/Title"); got != tt.want {
+				t.Errorf("pdfObject(%s %s) has title %q, want %q", tt.number, tt.generation, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnalyzePDF(t *testing.T) {
+	deflate := func(s string) string {
+		var b bytes.Buffer
+		w := zlib.NewWriter(&b)
+		w.Write([]byte(s))
+		w.Close()
+		return b.String()
+	}
+	objectStream := func(content string) string {
+		return fmt.Sprintf("5 0 obj\n<< /Type /ObjStm /N 1 /First 4 /Filter /FlateDecode /Length %d >>\nstream\n%s\nendstream\nendobj\n", len(content), content)
+	}
+
+	tests := []struct {
+		name string
+		data string
+		want models.PDFInfo
+	}{
+		{
+			name: "plain",
+			data: "%PDF-1.7\n" +
+				"1 0 obj << /Type /Catalog /Pages 2 0 R >> endobj\n" +
+				"2 0 obj << /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 >> endobj\n" +
+				"3 0 obj << /Type /Page /Annots [<< /A << /S /URI /URI (https://example.com/a) >> >>] >> endobj\n" +
+				"4 0 obj << /Type /Page /Annots [<< /A << /URI <68747470733A2F2F6578616D706C652E636F6D2F62> >> >>] >> endobj\n" +
+				"9 0 obj << /Title (Annual \\(draft\\) report) /Author <FEFF004A006F> >> endobj\n" +
+				"trailer << /Root 1 0 R /Info 9 0 R >>\n%%EOF",
+			want: models.PDFInfo{
+				Version: "1.7",
+				Pages:   2,
+				Title:   "Annual (draft) report",
+				Author:  "Jo",
+				Links:   []string{"https://example.com/a", "https://example.com/b"},
+			},
+		},
+		{
+			name: "pages counted without a page tree count",
+			data: "%PDF-1.3\n3 0 obj << /Type /Page >> endobj\n4 0 obj << /Type /Page >> endobj\n5 0 obj << /Type /Page >> endobj\n",
+			want: models.PDFInfo{Version: "1.3", Pages: 3, Links: []string{}},
+		},
+		{
+			name: "largest count is the page tree root",
+			data: "%PDF-1.5\n2 0 obj << /Type /Pages /Count 12 >> endobj\n6 0 obj << /Count 4 /Type /Pages /Parent 2 0 R >> endobj\n",
+			want: models.PDFInfo{Version: "1.5", Pages: 12, Links: []string{}},
+		},
+		{
+			name: "objects in a compressed object stream",
+			data: "%PDF-1.5\n" + objectStream(deflate("2 0 << /Type /Pages /Count 40 >> << /URI (https://example.com/packed) >>")),
+			want: models.PDFInfo{Version: "1.5", Pages: 40, Links: []string{"https://example.com/packed"}},
+		},
+		{
+			name: "encrypted",
+			data: "%PDF-1.6\n2 0 obj << /Type /Pages /Count 3 >> endobj\n9 0 obj << /Title (secret) >> endobj\ntrailer << /Info 9 0 R /Encrypt 8 0 R >>\n",
+			want: models.PDFInfo{Version: "1.6", Pages: 3, Links: []string{}, Encrypted: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := analyzePDF([]byte(tt.data)); !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("analyzePDF() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPDFObjectStreamsBudget(t *testing.T) {
+	var b bytes.Buffer
+	w := zlib.NewWriter(&b)
+	w.Write(make([]byte, pdfInflateLimit/2+1))
+	w.Close()
+	stream := fmt.Sprintf("<< /Type /ObjStm /Filter /FlateDecode >>\nstream\n%s\nendstream\n", b.String())
+
+	// Three streams of just over half the budget each inflate to the budget in total
+	data := []byte("%PDF-1.5\n1 0 obj " + stream + "endobj\n2 0 obj " + stream + "endobj\n3 0 obj " + stream + "endobj\n")
+	contents := pdfObjectStreams(data)
+	if inflated := len(bytes.ReplaceAll(contents, []byte("\n"), nil)); inflated != pdfInflateLimit {
+		t.Errorf("inflated %d bytes, want the budget of %d", inflated, pdfInflateLimit)
+	}
+}
+
+func TestAnalyzeImage(t *testing.T) {
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, image.NewGray(image.Rect(0, 0, 3, 2))); err != nil {
+		t.Fatal(err)
+	}
+
+	// A lossless WebP header with a 40x30 canvas
+	webp := []byte("RIFF\x00\x00\x00\x00WEBPVP8L\x00\x00\x00\x00\x2f")
+	bits := uint32(40-1) | uint32(30-1)<<14
+	webp = append(webp, byte(bits), byte(bits>>8), byte(bits>>16), byte(bits>>24))
+	webp = append(webp, make([]byte, 8)...)
+
+	tests := []struct {
+		name string
+		data []byte
+		want *models.ImageInfo
+	}{
+		{"png", pngData.Bytes(), &models.ImageInfo{Format: "png", Width: 3, Height: 2}},
+		{"webp", webp, &models.ImageInfo{Format: "webp", Width: 40, Height: 30}},
+		{"svg size", []byte(`<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg" width="120px" height="80"></svg>`), &models.ImageInfo{Format: "svg", Width: 120, Height: 80}},
+		{"svg viewBox", []byte(`<svg width="100%" viewBox="0 0 64,48"></svg>`), &models.ImageInfo{Format: "svg", Width: 64, Height: 48}},
+		{"svg without a size", []byte(`<svg></svg>`), nil},
+		{"not an image", []byte("hello"), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := analyzeImage(tt.data); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("analyzeImage() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnalyzeText(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		contentType string
+		want        models.TextInfo
+		mismatch    bool
+	}{
+		{"utf-8", "héllo\nworld\n", "text/plain; charset=utf-8", models.TextInfo{Length: 12, Lines: 2, Charset: "utf-8"}, false},
+		{"no trailing newline", "a\nb", "text/plain", models.TextInfo{Length: 3, Lines: 2, Charset: "utf-8"}, false},
+		{"declared latin-1", "caf\xE9", "text/plain; charset=iso-8859-1", models.TextInfo{Length: 4, Lines: 1, Charset: "windows-1252"}, false},
+		{"declared utf-8 but not", "caf\xE9", "text/plain; charset=utf-8", models.TextInfo{Length: 4, Lines: 1, Charset: "utf-8"}, true},
+		{"legacy bytes after the prescan", strings.Repeat("a", 2000) + "\xE9", "text/plain", models.TextInfo{Length: 2001, Lines: 1, Charset: "windows-1252"}, false},
+		{"empty", "", "application/json", models.TextInfo{Charset: "utf-8"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, charset := analyzeText([]byte(tt.data), tt.contentType)
+			if *text != tt.want || charset.Mismatch != tt.mismatch {
+				t.Errorf("analyzeText() = %+v (mismatch %v), want %+v (%v)", *text, charset.Mismatch, tt.want, tt.mismatch)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package crawler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"time"
+
+	"website-crawler/internal/models"
+)
+
+// maxPageRedirects is the hard cap on redirects followed for a crawled page
+const maxPageRedirects = 20
+
+// fetchResult holds the final response of a page fetch and how it was reached
+type fetchResult struct {
+	Response      *http.Response
+	FinalURL      *url.URL
+	RedirectChain []models.RedirectHop
+	RedirectLoop  bool
+	// DisallowedHop is the robots.txt verdict on a redirect target that
+	// robots.txt keeps us out of, which ends the fetch there
+	DisallowedHop *models.RobotsInfo
+	// Trace holds the timing of the request that produced Response
+	Trace *requestTrace
+}
+
+// fetchPage requests targetURL, following redirects by hand so every hop is
+// recorded. Unless ignoreRobots is set, each hop must be allowed by its own
+// host's robots.txt and waits out that host's Crawl-delay. On a redirect loop,
+// a disallowed hop or when the hard cap is reached it returns the chain so
+// far with a nil Response. The caller must close Response.Body.
+func (c *CrawlerService) fetchPage(ctx context.Context, targetURL string, ignoreRobots bool) (*fetchResult, error) {
+	currentURL, err := url.Parse(targetURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	result := &fetchResult{RedirectChain: []models.RedirectHop{}}
+	visited := map[string]bool{}
+
+	for {
+		visited[currentURL.String()] = true
+
+		req, err := http.NewRequestWithContext(ctx, "GET", currentURL.String(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; WebsiteCrawler/1.0)")
+		trace := newRequestTrace()
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace.clientTrace()))
+		resp, err := c.pageClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch URL: %w", err)
+		}
+		elapsed := time.Since(trace.start)
+
+		location, locErr := resp.Location()
+		if !isRedirect(resp.StatusCode) || locErr != nil {
+			result.Response = resp
+			result.FinalURL = currentURL
+			result.Trace = trace
+			return result, nil
+		}
+
+		// Drain a little so the connection can be reused for the next hop
+		io.CopyN(io.Discard, resp.Body, 4096)
+		resp.Body.Close()
+
+		result.RedirectChain = append(result.RedirectChain, models.RedirectHop{
+			URL:        currentURL.String(),
+			StatusCode: resp.StatusCode,
+			Location:   location.String(),
+			DurationMs: elapsed.Milliseconds(),
+		})
+
+		if visited[location.String()] {
+			result.RedirectLoop = true
+			return result, nil
+		}
+		if len(result.RedirectChain) >= maxPageRedirects {
+			return result, nil
+		}
+
+		currentURL = location
+		if !ignoreRobots {
+			robots, err := c.checkRobots(ctx, currentURL, false)
+			if err != nil {
+				return nil, err
+			}
+			if !robots.Allowed {
+				result.DisallowedHop = &robots
+				return result, nil
+			}
+			if !c.waitCrawlDelay(ctx, currentURL) {
+				return nil, ctx.Err()
+			}
+		}
+	}
+}
+
+func isRedirect(status int) bool {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// recordRedirects stores the redirect chain of a fetch on the analysis
+func (c *CrawlerService) recordRedirects(analysis *models.Analysis, fetched *fetchResult) {
+	if fetched.FinalURL != nil {
+		analysis.FinalURL = fetched.FinalURL.String()
+	}
+	analysis.RedirectCount = len(fetched.RedirectChain)
+	analysis.RedirectLoop = fetched.RedirectLoop
+	analysis.LongRedirectChain = c.redirectChainLimit > 0 && len(fetched.RedirectChain) > c.redirectChainLimit
+
+	if chainJSON, err := json.Marshal(fetched.RedirectChain); err == nil {
+		analysis.RedirectChain = string(chainJSON)
+	}
+}
@@ -0,0 +1,81 @@
+package crawler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"website-crawler/internal/models"
+)
+
+func TestCrawlWebsiteRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/old", http.RedirectHandler("/moved", http.StatusMovedPermanently))
+	mux.Handle("/moved", http.RedirectHandler("/page", http.StatusFound))
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<html><head><title>Landed</title></head><body></body></html>`))
+	})
+	mux.Handle("/ping", http.RedirectHandler("/pong", http.StatusTemporaryRedirect))
+	mux.Handle("/pong", http.RedirectHandler("/ping", http.StatusPermanentRedirect))
+	mux.Handle("/1", http.RedirectHandler("/2", http.StatusFound))
+	mux.Handle("/2", http.RedirectHandler("/3", http.StatusFound))
+	mux.Handle("/3", http.RedirectHandler("/4", http.StatusFound))
+	mux.Handle("/4", http.RedirectHandler("/page", http.StatusFound))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		path      string
+		finalPath string
+		title     string
+		hops      []int
+		loop      bool
+		long      bool
+	}{
+		{"no redirect", "/page", "/page", "Landed", []int{}, false, false},
+		{"two hops", "/old", "/page", "Landed", []int{301, 302}, false, false},
+		{"loop", "/ping", "", "", []int{307, 308}, true, false},
+		{"long chain", "/1", "/page", "Landed", []int{302, 302, 302, 302}, false, true},
+	}
+
+	c := NewCrawlerService()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analysis, err := c.CrawlWebsite(context.Background(), server.URL+tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var chain []models.RedirectHop
+			if err := json.Unmarshal([]byte(analysis.RedirectChain), &chain); err != nil {
+				t.Fatalf("redirect chain %q: %v", analysis.RedirectChain, err)
+			}
+			hops := []int{}
+			for _, hop := range chain {
+				hops = append(hops, hop.StatusCode)
+			}
+			if !reflect.DeepEqual(hops, tt.hops) || analysis.RedirectCount != len(tt.hops) {
+				t.Errorf("hops %v (count %d), want %v", hops, analysis.RedirectCount, tt.hops)
+			}
+			if len(chain) > 0 && chain[0].URL != server.URL+tt.path {
+				t.Errorf("first hop is %s, want %s", chain[0].URL, server.URL+tt.path)
+			}
+
+			wantFinal := ""
+			if tt.finalPath != "" {
+				wantFinal = server.URL + tt.finalPath
+			}
+			if analysis.FinalURL != wantFinal || analysis.Title != tt.title {
+				t.Errorf("final URL %q with title %q, want %q with %q", analysis.FinalURL, analysis.Title, wantFinal, tt.title)
+			}
+			if analysis.RedirectLoop != tt.loop || analysis.LongRedirectChain != tt.long {
+				t.Errorf("loop %v, long chain %v, want %v and %v", analysis.RedirectLoop, analysis.LongRedirectChain, tt.loop, tt.long)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package crawler
+
+import (
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"website-crawler/internal/models"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// csrfFieldNames matches the hidden token fields common frameworks add to forms
+var csrfFieldNames = regexp.MustCompile(`(?i)(csrf|xsrf|authenticity_token|requestverificationtoken|^_token$|^__token$|form_key|nonce)`)
+
+// inventoryForms lists every form on the page with its fields and flags forms
+// that leak passwords or submit to another site
+func (c *CrawlerService) inventoryForms(doc *goquery.Document, pageURL *url.URL) []models.FormInfo {
+	forms := []models.FormInfo{}
+
+	// Single-page apps often send the token from a meta tag instead of a field
+	metaToken := ""
+	doc.Find("meta[name]").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		if name := s.AttrOr("name", ""); csrfFieldNames.MatchString(name) {
+			metaToken = name
+			return false
+		}
+		return true
+	})
+
+	doc.Find("form").Each(func(i int, s *goquery.Selection) {
+		form := models.FormInfo{
+			Element:      cssPath(s),
+			Method:       strings.ToUpper(strings.TrimSpace(s.AttrOr("method", "get"))),
+			Fields:       []models.FormField{},
+			Autocomplete: strings.ToLower(strings.TrimSpace(s.AttrOr("autocomplete", "on"))),
+			Issues:       []models.FormIssue{},
+		}
+		if form.Method != "POST" && form.Method != "DIALOG" {
+			form.Method = "GET"
+		}
+		addIssue := func(code, severity, message string) {
+			form.Issues = append(form.Issues, models.FormIssue{Code: code, Severity: severity, Message: message})
+		}
+
+		s.Find("input, select, textarea").Each(func(j int, field *goquery.Selection) {
+			fieldType := goquery.NodeName(field)
+			if fieldType == "input" {
+				fieldType = strings.ToLower(strings.TrimSpace(field.AttrOr("type", "text")))
+			}
+			name := field.AttrOr("name", "")
+			_, required := field.Attr("required")
+
+			if fieldType == "password" {
+				form.HasPassword = true
+			}
+			if fieldType == "hidden" && form.CSRFToken == "" && csrfFieldNames.MatchString(name) {
+				form.CSRFToken = name
+			}
+			form.Fields = append(form.Fields, models.FormField{
+				Name:         name,
+				Type:         fieldType,
+				Autocomplete: field.AttrOr("autocomplete", ""),
+				Required:     required,
+			})
+		})
+		if form.CSRFToken == "" && metaToken != "" {
+			form.CSRFToken = metaToken
+		}
+
+		actionURL, ok := resolveReference(pageURL, s.AttrOr("action", ""))
+		if !ok {
+			// javascript: and other non-http actions are handled by scripts
+			form.Action = strings.TrimSpace(s.AttrOr("action", ""))
+		} else {
+			actionURL.Fragment = ""
+			form.Action = actionURL.String()
+			form.CrossOrigin = actionURL.Scheme != pageURL.Scheme || actionURL.Host != pageURL.Host
+
+			if form.HasPassword && actionURL.Scheme == "http" {
+				addIssue("password_over_http", models.SeverityHigh, "The password is submitted over unencrypted http to "+form.Action)
+			}
+			switch {
+			case actionURL.Hostname() != pageURL.Hostname() && form.HasPassword:
+				addIssue("cross_origin_action", models.SeverityHigh, "The password is submitted to another site, "+actionURL.Host)
+			case actionURL.Hostname() != pageURL.Hostname():
+				addIssue("cross_origin_action", models.SeverityMedium, "The form submits to another site, "+actionURL.Host)
+			case form.CrossOrigin && pageURL.Scheme == "https":
+				addIssue("cross_origin_action", models.SeverityLow, "The form submits to a different origin, "+actionURL.Scheme+"://"+actionURL.Host)
+			}
+		}
+
+		if form.HasPassword && form.Method == "GET" {
+			addIssue("password_in_url", models.SeverityHigh, "The form sends the password with GET, exposing it in the URL, logs and history")
+		}
+		if form.Method == "POST" && form.CSRFToken == "" && !form.CrossOrigin {
+			addIssue("csrf_token_missing", models.SeverityLow, "The POST form has no anti-CSRF token field")
+		}
+		if form.HasPassword && form.Autocomplete == "off" {
+			addIssue("autocomplete_off", models.SeverityInfo, "autocomplete=off on a password form stops password managers but not attackers")
+		}
+
+		forms = append(forms, form)
+	})
+
+	return forms
+}
+
+func (c *CrawlerService) formsJSON(forms []models.FormInfo) string {
+	if jsonData, err := json.Marshal(forms); err == nil {
+		return string(jsonData)
+	}
+	return "[]"
+}
+
+// countInsecureForms returns how many forms have a high severity issue
+func countInsecureForms(forms []models.FormInfo) int {
+	count := 0
+	for _, form := range forms {
+		for _, issue := range form.Issues {
+			if issue.Severity == models.SeverityHigh {
+				count++
+				break
+			}
+		}
+	}
+	return count
+}
@@ -0,0 +1,115 @@
+package crawler
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+
+	"website-crawler/internal/models"
+)
+
+func TestInventoryForms(t *testing.T) {
+	tests := []struct {
+		name        string
+		pageURL     string
+		body        string
+		method      string
+		action      string
+		crossOrigin bool
+		csrf        string
+		issues      map[string]string // code to severity
+	}{
+		{
+			"safe login",
+			"https://example.com/login",
+			`<form method="post" action="/session"><input type="hidden" name="authenticity_token" value="x">
+<input type="email" name="email" required><input type="password" name="password" autocomplete="current-password"></form>`,
+			"POST", "https://example.com/session", false, "authenticity_token", map[string]string{},
+		},
+		{
+			"password over http",
+			"https://example.com/login",
+			`<form method="POST" action="http://example.com/session"><input name="_token" type="hidden"><input type="password" name="pw"></form>`,
+			"POST", "http://example.com/session", true, "_token", map[string]string{"password_over_http": models.SeverityHigh, "cross_origin_action": models.SeverityLow},
+		},
+		{
+			"password to another site with get",
+			"https://example.com/",
+			`<form action="https://collector.example.net/grab#x" autocomplete="off"><input name="user"><input type="password" name="pw"></form>`,
+			"GET", "https://collector.example.net/grab", true, "",
+			map[string]string{"cross_origin_action": models.SeverityHigh, "password_in_url": models.SeverityHigh, "autocomplete_off": models.SeverityInfo},
+		},
+		{
+			"post without a token",
+			"https://example.com/contact",
+			`<form method="post"><textarea name="message"></textarea><select name="topic"></select></form>`,
+			"POST", "https://example.com/contact", false, "", map[string]string{"csrf_token_missing": models.SeverityLow},
+		},
+		{
+			"newsletter to another site",
+			"https://example.com/",
+			`<form method="post" action="https://lists.example.org/subscribe"><input type="email" name="email"></form>`,
+			"POST", "https://lists.example.org/subscribe", true, "", map[string]string{"cross_origin_action": models.SeverityMedium},
+		},
+		{
+			"script action and unknown method",
+			"http://example.com/",
+			`<form method="put" action="javascript:void(0)"><input type="text" name="q"></form>`,
+			"GET", "javascript:void(0)", false, "", map[string]string{},
+		},
+	}
+
+	c := NewCrawlerService()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pageURL, _ := url.Parse(tt.pageURL)
+			forms := c.inventoryForms(newTestDocument(t, `<html><body>`+tt.body+`</body></html>`), pageURL)
+			if len(forms) != 1 {
+				t.Fatalf("got %d forms, want 1", len(forms))
+			}
+
+			form := forms[0]
+			if form.Method != tt.method || form.Action != tt.action || form.CrossOrigin != tt.crossOrigin || form.CSRFToken != tt.csrf {
+				t.Errorf("%s %s (cross origin %v, token %q), want %s %s (%v, %q)",
+					form.Method, form.Action, form.CrossOrigin, form.CSRFToken, tt.method, tt.action, tt.crossOrigin, tt.csrf)
+			}
+			issues := map[string]string{}
+			for _, issue := range form.Issues {
+				issues[issue.Code] = issue.Severity
+			}
+			if !reflect.DeepEqual(issues, tt.issues) {
+				t.Errorf("issues %v, want %v", issues, tt.issues)
+			}
+		})
+	}
+}
+
+func TestInventoryFormsFields(t *testing.T) {
+	pageURL, _ := url.Parse("https://example.com/")
+	doc := newTestDocument(t, `<html><head><meta name="csrf-token" content="abc"></head><body>
+<form method="post"><input name="email" type="EMAIL" autocomplete="username" required><select name="plan"></select>
+<textarea name="notes"></textarea><input name="password" type="password"></form>
+<form method="post" action="https://other.example.org/"><input name="q"></form>
+</body></html>`)
+
+	forms := NewCrawlerService().inventoryForms(doc, pageURL)
+	if len(forms) != 2 {
+		t.Fatalf("got %d forms, want 2", len(forms))
+	}
+
+	want := []models.FormField{
+		{Name: "email", Type: "email", Autocomplete: "username", Required: true},
+		{Name: "plan", Type: "select"},
+		{Name: "notes", Type: "textarea"},
+		{Name: "password", Type: "password"},
+	}
+	if !reflect.DeepEqual(forms[0].Fields, want) || !forms[0].HasPassword {
+		t.Errorf("fields %+v, want %+v", forms[0].Fields, want)
+	}
+	if forms[0].CSRFToken != "csrf-token" || len(forms[0].Issues) != 0 {
+		t.Errorf("the meta token should cover the form: token %q, issues %+v", forms[0].CSRFToken, forms[0].Issues)
+	}
+	if got := countInsecureForms(forms); got != 0 {
+		t.Errorf("%d insecure forms, want 0", got)
+	}
+}
@@ -0,0 +1,390 @@
+package crawler
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"website-crawler/internal/models"
+)
+
+// linkCheckResult holds the outcome of checking a single link
+type linkCheckResult struct {
+	StatusCode int
+	Err        error
+	// Checked is false when the link was skipped because the time budget ran
+	// out, or the host was still rate limiting after every retry
+	Checked   bool
+	Attempts  int
+	CheckedAt time.Time
+	// Size is the resource's size from Content-Length or Content-Range, -1 when unknown
+	Size        int64
+	ContentType string
+}
+
+var (
+	errRedirectLoop     = errors.New("redirect loop detected")
+	errTooManyRedirects = errors.New("too many redirects")
+)
+
+// requestThrottle waits until a request may be sent to the URL's host,
+// returning false if the context ends first
+type requestThrottle func(ctx context.Context, target *url.URL) bool
+
+// maxLinkRedirects is the number of redirects followed before a link is reported broken
+const maxLinkRedirects = 10
+
+// linkChecker verifies links with a bounded worker pool, limiting both the
+// total and the per-host number of requests in flight
+type linkChecker struct {
+	client        *http.Client
+	concurrency   int
+	perHost       int
+	timeout       time.Duration
+	budget        time.Duration
+	retries       int
+	backoff       time.Duration
+	maxRetryAfter time.Duration
+
+	mutex     sync.Mutex
+	hostSlots map[string]*hostLimit
+}
+
+// hostLimit limits the requests in flight to one host. users counts the
+// checks holding or waiting for a slot.
+type hostLimit struct {
+	slots chan struct{}
+	users int
+}
+
+func newLinkChecker(client *http.Client, cfg Config) *linkChecker {
+	checkClient := *client
+	checkClient.CheckRedirect = checkLinkRedirect
+
+	return &linkChecker{
+		client:        &checkClient,
+		concurrency:   max(cfg.LinkCheckConcurrency, 1),
+		perHost:       max(cfg.LinkCheckPerHost, 1),
+		timeout:       cfg.LinkCheckTimeout,
+		budget:        cfg.LinkCheckBudget,
+		retries:       max(cfg.LinkCheckRetries, 0),
+		backoff:       cfg.LinkCheckBackoff,
+		maxRetryAfter: cfg.LinkCheckMaxRetryAfter,
+		hostSlots:     make(map[string]*hostLimit),
+	}
+}
+
+// checkAll checks every link and returns the results keyed by link. Links
+// that could not be checked before the budget expired have Checked set to
+// false. throttle, when not nil, paces every request sent.
+func (lc *linkChecker) checkAll(ctx context.Context, links []*url.URL, throttle requestThrottle) map[string]linkCheckResult {
+	if lc.budget > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, lc.budget)
+		defer cancel()
+	}
+
+	results := make(map[string]linkCheckResult, len(links))
+	var resultsMutex sync.Mutex
+	forEachIndex(len(links), lc.concurrency, func(index int) {
+		result := lc.checkWithHostLimit(ctx, links[index], throttle)
+		resultsMutex.Lock()
+		results[links[index].String()] = result
+		resultsMutex.Unlock()
+	})
+
+	return results
+}
+
+// checkWithHostLimit waits for a free slot on the link's host before checking it
+func (lc *linkChecker) checkWithHostLimit(ctx context.Context, link *url.URL, throttle requestThrottle) linkCheckResult {
+	release, ok := lc.acquireHost(ctx, link.Host)
+	if !ok {
+		return linkCheckResult{}
+	}
+	defer release()
+
+	result := lc.check(ctx, link, throttle)
+	if result.Err != nil && ctx.Err() != nil {
+		// The overall budget ran out, so the failure says nothing about the link
+		return linkCheckResult{}
+	}
+	return result
+}
+
+// acquireHost waits for a free request slot on a host, returning false if
+// the context ends first. A host's slots are dropped once nobody holds or
+// waits for them, so the map only covers hosts currently being checked.
+func (lc *linkChecker) acquireHost(ctx context.Context, host string) (func(), bool) {
+	lc.mutex.Lock()
+	limit, ok := lc.hostSlots[host]
+	if !ok {
+		limit = &hostLimit{slots: make(chan struct{}, lc.perHost)}
+		lc.hostSlots[host] = limit
+	}
+	limit.users++
+	lc.mutex.Unlock()
+
+	leave := func() {
+		lc.mutex.Lock()
+		defer lc.mutex.Unlock()
+		limit.users--
+		if limit.users == 0 {
+			delete(lc.hostSlots, host)
+		}
+	}
+
+	select {
+	case limit.slots <- struct{}{}:
+		return func() {
+			<-limit.slots
+			leave()
+		}, true
+	case <-ctx.Done():
+		leave()
+		return nil, false
+	}
+}
+
+// check verifies a link, retrying transient failures with backoff
+func (lc *linkChecker) check(ctx context.Context, link *url.URL, throttle requestThrottle) linkCheckResult {
+	var result linkCheckResult
+	var delay time.Duration
+
+	for attempt := 1; attempt <= lc.retries+1; attempt++ {
+		if attempt > 1 {
+			timer := time.NewTimer(delay)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				// The budget ran out before the retry, so the transient
+				// failure says nothing about the link
+				result.Checked = false
+				return result
+			}
+		}
+
+		resp, err := lc.probe(ctx, link, throttle)
+		result = linkCheckResult{
+			Err:       err,
+			Checked:   true,
+			Attempts:  attempt,
+			CheckedAt: time.Now(),
+			Size:      -1,
+		}
+		var retryAfterHeader string
+		if resp != nil {
+			result.StatusCode = resp.StatusCode
+			result.Size = responseSize(resp)
+			result.ContentType = resp.Header.Get("Content-Type")
+			retryAfterHeader = resp.Header.Get("Retry-After")
+		}
+		if !isTransient(result.StatusCode, retryAfterHeader != "", err) {
+			return result
+		}
+
+		delay = lc.backoff * time.Duration(1<<(attempt-1))
+		if retryAfter := parseRetryAfter(retryAfterHeader); retryAfter > 0 {
+			delay = retryAfter
+		}
+		if delay > lc.maxRetryAfter {
+			delay = lc.maxRetryAfter
+		}
+	}
+
+	// A host that is still rate limiting says nothing about the link itself
+	if result.StatusCode == http.StatusTooManyRequests {
+		result.Checked = false
+	}
+	return result
+}
+
+// probe asks for the link with HEAD and falls back to a ranged GET when the
+// server rejects HEAD or answers it with an error the GET may not share. The
+// returned response's body is already closed.
+func (lc *linkChecker) probe(ctx context.Context, link *url.URL, throttle requestThrottle) (*http.Response, error) {
+	do := func(method string, ranged bool) (*http.Response, error) {
+		if throttle != nil && !throttle(ctx, link) {
+			return nil, ctx.Err()
+		}
+		return lc.do(ctx, method, link, ranged)
+	}
+
+	resp, err := do(http.MethodHead, false)
+	if err == nil {
+		resp.Body.Close()
+		if !needsGETFallback(resp.StatusCode) {
+			return resp, nil
+		}
+	} else if isTimeout(err) || isConnectFailure(err) {
+		return nil, err
+	}
+
+	resp, err = do(http.MethodGet, true)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	// Some servers refuse the range for empty or dynamic resources
+	if resp.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		resp, err = do(http.MethodGet, false)
+		if err != nil {
+			return nil, err
+		}
+		resp.Body.Close()
+	}
+
+	return resp, nil
+}
+
+// responseSize returns the full size of the resource behind a response,
+// reading the total from Content-Range for ranged responses
+func responseSize(resp *http.Response) int64 {
+	if resp.StatusCode == http.StatusPartialContent {
+		// bytes 0-0/12345
+		_, total, ok := strings.Cut(resp.Header.Get("Content-Range"), "/")
+		if size, err := strconv.ParseInt(strings.TrimSpace(total), 10, 64); ok && err == nil {
+			return size
+		}
+		return -1
+	}
+	if resp.StatusCode >= 300 {
+		return -1
+	}
+	return resp.ContentLength
+}
+
+// do sends a single request without reading the response body
+func (lc *linkChecker) do(ctx context.Context, method string, link *url.URL, ranged bool) (*http.Response, error) {
+	if lc.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, lc.timeout)
+		// The body is closed unread, so the context can be released once headers arrive
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, link.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; WebsiteCrawler/1.0)")
+	if ranged {
+		req.Header.Set("Range", "bytes=0-0")
+	}
+	return lc.client.Do(req)
+}
+
+// checkLinkRedirect stops redirect chains that revisit a URL or grow too long
+func checkLinkRedirect(req *http.Request, via []*http.Request) error {
+	for _, prev := range via {
+		if prev.URL.String() == req.URL.String() {
+			return errRedirectLoop
+		}
+	}
+	if len(via) >= maxLinkRedirects {
+		return errTooManyRedirects
+	}
+	return nil
+}
+
+// classifyFailure maps a failed check to one of the models.Failure* categories
+func classifyFailure(status int, err error) string {
+	if err == nil {
+		switch {
+		case status >= 500:
+			return models.FailureServerError
+		case status >= 400:
+			return models.FailureClientError
+		}
+		return ""
+	}
+
+	var dnsErr *net.DNSError
+	var certErr *tls.CertificateVerificationError
+	var recordErr tls.RecordHeaderError
+	var alertErr tls.AlertError
+	var unknownAuthErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidCertErr x509.CertificateInvalidError
+
+	switch {
+	case errors.Is(err, errRedirectLoop):
+		return models.FailureRedirectLoop
+	case errors.Is(err, errTooManyRedirects):
+		return models.FailureTooManyRedirects
+	case errors.As(err, &dnsErr):
+		return models.FailureDNS
+	case isTimeout(err):
+		return models.FailureTimeout
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return models.FailureConnRefused
+	case errors.As(err, &certErr), errors.As(err, &recordErr), errors.As(err, &alertErr),
+		errors.As(err, &unknownAuthErr), errors.As(err, &hostnameErr), errors.As(err, &invalidCertErr):
+		return models.FailureTLS
+	}
+	return models.FailureOther
+}
+
+// needsGETFallback reports whether a HEAD status should be confirmed with GET.
+// 405 and 501 mean HEAD is unsupported; other errors are often HEAD-specific.
+// Rate limiting and unavailability concern the server rather than HEAD.
+func needsGETFallback(status int) bool {
+	if status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable {
+		return false
+	}
+	return status >= 400
+}
+
+// isTransient reports whether a check outcome is worth retrying. A 503 is
+// only retried when the server sends Retry-After to say it will be back.
+func isTransient(status int, retryAfter bool, err error) bool {
+	if err != nil {
+		return isTimeout(err)
+	}
+	return status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable && retryAfter
+}
+
+// isConnectFailure reports whether a request failed before reaching the
+// server, so asking again with another method cannot help
+func isConnectFailure(err error) bool {
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	return errors.As(err, &dnsErr) || errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if d := time.Until(date); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,280 @@
+package crawler
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"website-crawler/internal/models"
+)
+
+func parseLinks(t *testing.T, base string, paths ...string) []*url.URL {
+	t.Helper()
+	var links []*url.URL
+	for _, path := range paths {
+		link, err := url.Parse(base + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		links = append(links, link)
+	}
+	return links
+}
+
+func TestLinkCheckerCheckAll(t *testing.T) {
+	var mutex sync.Mutex
+	requests := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		requests[r.URL.Path]++
+		count := requests[r.URL.Path]
+		mutex.Unlock()
+
+		switch r.URL.Path {
+		case "/ok":
+			w.WriteHeader(http.StatusOK)
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/error":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/flaky":
+			// Unavailable once, then fine
+			if count == 1 {
+				w.Header().Set("Retry-After", "1")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		case "/down":
+			// Unavailable without saying for how long, so not worth a retry
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "/limited":
+			w.WriteHeader(http.StatusTooManyRequests)
+		case "/no-head":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+
+	cfg := DefaultConfig()
+	cfg.LinkCheckRetries = 1
+	cfg.LinkCheckBackoff = time.Millisecond
+	cfg.LinkCheckMaxRetryAfter = time.Millisecond
+	lc := newLinkChecker(server.Client(), cfg)
+
+	tests := []struct {
+		path     string
+		status   int
+		checked  bool
+		attempts int
+	}{
+		{"/ok", http.StatusOK, true, 1},
+		{"/missing", http.StatusNotFound, true, 1},
+		{"/error", http.StatusInternalServerError, true, 1},
+		{"/flaky", http.StatusOK, true, 2},
+		{"/down", http.StatusServiceUnavailable, true, 1},
+		{"/no-head", http.StatusOK, true, 1},
+		// Still rate limited after the retry, so nothing is known about the link
+		{"/limited", http.StatusTooManyRequests, false, 2},
+	}
+
+	var paths []string
+	for _, tt := range tests {
+		paths = append(paths, tt.path)
+	}
+	links := parseLinks(t, server.URL, paths...)
+
+	results := lc.checkAll(context.Background(), links, nil)
+	for i, tt := range tests {
+		result := results[links[i].String()]
+		if result.StatusCode != tt.status || result.Checked != tt.checked || result.Attempts != tt.attempts {
+			t.Errorf("%s: status %d, checked %v after %d attempts, want %d, %v after %d",
+				tt.path, result.StatusCode, result.Checked, result.Attempts, tt.status, tt.checked, tt.attempts)
+		}
+	}
+}
+
+func TestLinkCheckerPerHostLimit(t *testing.T) {
+	var mutex sync.Mutex
+	inFlight, peak := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		inFlight++
+		peak = max(peak, inFlight)
+		mutex.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mutex.Lock()
+		inFlight--
+		mutex.Unlock()
+	}))
+	defer server.Close()
+
+	cfg := DefaultConfig()
+	cfg.LinkCheckConcurrency = 10
+	cfg.LinkCheckPerHost = 2
+	lc := newLinkChecker(server.Client(), cfg)
+
+	var paths []string
+	for i := 0; i < 12; i++ {
+		paths = append(paths, fmt.Sprintf("/page-%d", i))
+	}
+	results := lc.checkAll(context.Background(), parseLinks(t, server.URL, paths...), nil)
+
+	if len(results) != len(paths) {
+		t.Errorf("got %d results, want %d", len(results), len(paths))
+	}
+	if peak > cfg.LinkCheckPerHost {
+		t.Errorf("%d requests ran against the host at once, want at most %d", peak, cfg.LinkCheckPerHost)
+	}
+	if len(lc.hostSlots) != 0 {
+		t.Errorf("%d hosts still hold slots after the check finished", len(lc.hostSlots))
+	}
+}
+
+func TestLinkCheckerBudget(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	cfg := DefaultConfig()
+	cfg.LinkCheckBudget = 50 * time.Millisecond
+	lc := newLinkChecker(server.Client(), cfg)
+
+	links := parseLinks(t, server.URL, "/slow-1", "/slow-2")
+	for link, result := range lc.checkAll(context.Background(), links, nil) {
+		if result.Checked {
+			t.Errorf("%s was reported as checked after the budget ran out: %+v", link, result)
+		}
+	}
+}
+
+func TestLinkCheckerBudgetDuringBackoff(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "10")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	cfg := DefaultConfig()
+	cfg.LinkCheckBudget = 50 * time.Millisecond
+	cfg.LinkCheckMaxRetryAfter = time.Minute
+	lc := newLinkChecker(server.Client(), cfg)
+
+	for link, result := range lc.checkAll(context.Background(), parseLinks(t, server.URL, "/busy"), nil) {
+		if result.Checked {
+			t.Errorf("%s was reported as checked when the budget ran out waiting to retry: %+v", link, result)
+		}
+	}
+}
+
+// roundTripFunc lets a test stand in for the network
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestLinkCheckerConnectFailure(t *testing.T) {
+	var methods []string
+	lc := newLinkChecker(&http.Client{}, DefaultConfig())
+	lc.client.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		methods = append(methods, req.Method)
+		return nil, &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: req.URL.Hostname(), IsNotFound: true}}
+	})
+
+	result := lc.check(context.Background(), parseLinks(t, "https://example.invalid", "/page")[0], nil)
+	if result.Err == nil || classifyFailure(0, result.Err) != models.FailureDNS {
+		t.Errorf("want a DNS failure, got %+v", result)
+	}
+	if len(methods) != 1 || methods[0] != http.MethodHead {
+		t.Errorf("sent %v, want a single HEAD with no GET fallback", methods)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		min   time.Duration
+		max   time.Duration
+	}{
+		{"empty", "", 0, 0},
+		{"seconds", "5", 5 * time.Second, 5 * time.Second},
+		{"padded seconds", " 120 ", 2 * time.Minute, 2 * time.Minute},
+		{"zero", "0", 0, 0},
+		{"negative", "-3", 0, 0},
+		{"garbage", "soon", 0, 0},
+		{"past date", "Wed, 21 Oct 2015 07:28:00 GMT", 0, 0},
+		{"future date", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat), 58 * time.Minute, time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseRetryAfter(tt.value)
+			if got < tt.min || got > tt.max {
+				t.Errorf("parseRetryAfter(%q) = %v, want between %v and %v", tt.value, got, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestClassifyFailure(t *testing.T) {
+	wrap := func(err error) error {
+		return &url.Error{Op: "Head", URL: "https://example.com/", Err: err}
+	}
+
+	tests := []struct {
+		name   string
+		status int
+		err    error
+		want   string
+	}{
+		{"ok", http.StatusOK, nil, ""},
+		{"redirect", http.StatusMovedPermanently, nil, ""},
+		{"not found", http.StatusNotFound, nil, models.FailureClientError},
+		{"server error", http.StatusBadGateway, nil, models.FailureServerError},
+		{"redirect loop", 0, wrap(errRedirectLoop), models.FailureRedirectLoop},
+		{"too many redirects", 0, wrap(errTooManyRedirects), models.FailureTooManyRedirects},
+		{"dns", 0, wrap(&net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "example.invalid", IsNotFound: true}}), models.FailureDNS},
+		{"deadline", 0, wrap(context.DeadlineExceeded), models.FailureTimeout},
+		{"network timeout", 0, wrap(&net.OpError{Op: "dial", Err: os.ErrDeadlineExceeded}), models.FailureTimeout},
+		{"connection refused", 0, wrap(&net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}), models.FailureConnRefused},
+		{"certificate verification", 0, wrap(&tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}}), models.FailureTLS},
+		{"unknown authority", 0, wrap(x509.UnknownAuthorityError{}), models.FailureTLS},
+		{"hostname mismatch", 0, wrap(x509.HostnameError{Host: "example.com"}), models.FailureTLS},
+		{"invalid certificate", 0, wrap(x509.CertificateInvalidError{Reason: x509.Expired}), models.FailureTLS},
+		{"not tls", 0, wrap(tls.RecordHeaderError{Msg: "first record does not look like a TLS handshake"}), models.FailureTLS},
+		{"handshake alert", 0, wrap(fmt.Errorf("remote error: %w", tls.AlertError(40))), models.FailureTLS},
+		{"tls in the message only", 0, wrap(errors.New("tls: something else")), models.FailureOther},
+		{"other", 0, wrap(errors.New("connection reset")), models.FailureOther},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyFailure(tt.status, tt.err); got != tt.want {
+				t.Errorf("classifyFailure(%d, %v) = %q, want %q", tt.status, tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package crawler
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	nethtml "golang.org/x/net/html"
+)
+
+// cssPath builds a selector that uniquely locates an element in the document,
+// anchored at the nearest ancestor with an id
+func cssPath(s *goquery.Selection) string {
+	if s.Length() == 0 {
+		return ""
+	}
+
+	var parts []string
+	for node := s.Get(0); node != nil && node.Type == nethtml.ElementNode; node = node.Parent {
+		if id := attrValue(node, "id"); id != "" {
+			parts = append(parts, node.Data+"#"+id)
+			break
+		}
+
+		part := node.Data
+		if node.Parent != nil && node.Parent.Type == nethtml.ElementNode {
+			index, total := 0, 0
+			for sibling := node.Parent.FirstChild; sibling != nil; sibling = sibling.NextSibling {
+				if sibling.Type != nethtml.ElementNode || sibling.Data != node.Data {
+					continue
+				}
+				total++
+				if sibling == node {
+					index = total
+				}
+			}
+			if total > 1 {
+				part = fmt.Sprintf("%s:nth-of-type(%d)", node.Data, index)
+			}
+		}
+		parts = append(parts, part)
+	}
+
+	for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+		parts[i], parts[j] = parts[j], parts[i]
+	}
+	return strings.Join(parts, " > ")
+}
+
+func attrValue(node *nethtml.Node, name string) string {
+	value, _ := nodeAttr(node, name)
+	return value
+}
+
+func nodeAttr(node *nethtml.Node, name string) (string, bool) {
+	for _, attr := range node.Attr {
+		if attr.Key == name {
+			return attr.Val, true
+		}
+	}
+	return "", false
+}
+
+// sourceLocator estimates the source line of attribute values. The HTML parser
+// does not keep positions, so values are found by searching the raw markup in
+// document order; repeated values resume from the previous match.
+type sourceLocator struct {
+	source     string
+	lineStarts []int
+	cursors    map[string]int
+}
+
+func newSourceLocator(source string) *sourceLocator {
+	lineStarts := []int{0}
+	for i := 0; i < len(source); i++ {
+		if source[i] == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	return &sourceLocator{source: source, lineStarts: lineStarts, cursors: make(map[string]int)}
+}
+
+// Line returns the approximate 1-based line of the next occurrence of value,
+// or 0 if it cannot be found
+func (l *sourceLocator) Line(value string) int {
+	if l == nil || value == "" {
+		return 0
+	}
+
+	start := l.cursors[value]
+	pos, end := -1, 0
+	for _, candidate := range []string{value, html.EscapeString(value)} {
+		offset := strings.Index(l.source[start:], candidate)
+		if offset >= 0 && (pos < 0 || start+offset < pos) {
+			pos = start + offset
+			end = pos + len(candidate)
+		}
+	}
+	if pos < 0 {
+		return 0
+	}
+
+	l.cursors[value] = end
+	return sort.SearchInts(l.lineStarts, pos+1)
+}
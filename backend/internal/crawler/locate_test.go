@@ -0,0 +1,60 @@
+package crawler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestCSSPath(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<html><body>
+<div id="nav"><ul><li><a class="first">One</a></li><li><a class="second">Two</a></li></ul></div>
+<p><a class="lone">Lone</a></p><p><span><a class="nested">Nested</a></span></p>
+</body></html>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		selector string
+		want     string
+	}{
+		{"a.first", "div#nav > ul > li:nth-of-type(1) > a"},
+		{"a.second", "div#nav > ul > li:nth-of-type(2) > a"},
+		{"a.lone", "html > body > p:nth-of-type(1) > a"},
+		{"a.nested", "html > body > p:nth-of-type(2) > span > a"},
+		{"a.missing", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			if got := cssPath(doc.Find(tt.selector)); got != tt.want {
+				t.Errorf("cssPath(%s) = %q, want %q", tt.selector, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSourceLocatorLine(t *testing.T) {
+	source := "<html>\n<a href=\"/page\">Page</a>\n<a href=\"/b?x=1&amp;y=2\">B</a>\n<a href=\"/page\">Page again</a>\n</html>"
+	locator := newSourceLocator(source)
+
+	tests := []struct {
+		value string
+		want  int
+	}{
+		{"/page", 2},
+		{"/b?x=1&y=2", 3},
+		{"/page", 4},
+		{"/page", 0},
+		{"/missing", 0},
+		{"", 0},
+	}
+
+	for _, tt := range tests {
+		if got := locator.Line(tt.value); got != tt.want {
+			t.Errorf("Line(%q) = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
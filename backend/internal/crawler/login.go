@@ -0,0 +1,339 @@
+package crawler
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"website-crawler/internal/models"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// loginThreshold is the confidence above which a page is said to have a login form
+const loginThreshold = 0.5
+
+var (
+	loginWords        = regexp.MustCompile(`\b(log ?in|sign ?in|log ?on)\b`)
+	registrationWords = regexp.MustCompile(`\b(sign ?up|register|registration|create (an |your )?account|join)\b`)
+	resetWords        = regexp.MustCompile(`\b(forgot|forgotten|reset|recover|lost)\b`)
+	changeWords       = regexp.MustCompile(`\b((change|update) (your )?password|(set|choose) (a )?new password)\b`)
+
+	usernameField = regexp.MustCompile(`\b(user ?name|user ?id|user|email|e ?mail|login|account|phone)\b`)
+	otpField      = regexp.MustCompile(`\b(otp|totp|2fa|mfa|one ?time|verification ?code|auth(entication)? ?code|security ?code|passcode)\b`)
+	personalField = regexp.MustCompile(`\b(first ?name|last ?name|full ?name|given ?name|family ?name|birth ?day|date of birth|dob|address)\b`)
+	termsField    = regexp.MustCompile(`\b(terms|agree|accept|consent|newsletter)\b`)
+	rememberField = regexp.MustCompile(`\b(remember|keep me|stay signed)\b`)
+
+	ssoButton = regexp.MustCompile(`\b(sign[ -]?in|log[ -]?in|continue|sign[ -]?up) (with|using|via) ([a-z0-9]+)`)
+
+	// ssoNames maps the provider named on a sign-in button to its display name
+	ssoNames = map[string]string{
+		"google": "Google", "facebook": "Facebook", "apple": "Apple", "microsoft": "Microsoft",
+		"github": "GitHub", "gitlab": "GitLab", "twitter": "Twitter", "x": "X",
+		"linkedin": "LinkedIn", "okta": "Okta", "amazon": "Amazon", "slack": "Slack",
+		"discord": "Discord", "sso": "SSO", "saml": "SSO",
+	}
+	ssoEndpoints = map[string]string{
+		"accounts.google.com":       "Google",
+		"login.microsoftonline.com": "Microsoft",
+		"login.live.com":            "Microsoft",
+		"github.com/login/oauth":    "GitHub",
+		"facebook.com/dialog/oauth": "Facebook",
+		"appleid.apple.com/auth":    "Apple",
+		"linkedin.com/oauth":        "LinkedIn",
+		"api.twitter.com/oauth":     "Twitter",
+		"twitter.com/i/oauth2":      "Twitter",
+	}
+)
+
+// authCandidate accumulates the per-kind scores and evidence of one form
+type authCandidate struct {
+	scores   map[string]float64
+	evidence map[string][]string
+	methods  map[string]bool
+}
+
+func (a *authCandidate) add(kind string, weight float64, evidence string) {
+	a.scores[kind] += weight
+	a.evidence[kind] = append(a.evidence[kind], evidence)
+}
+
+// detectLogin scores every form on the page as a login, registration,
+// password reset or password change form, and collects single sign-on buttons
+func (c *CrawlerService) detectLogin(doc *goquery.Document) models.LoginDetection {
+	providers, signIn := ssoProviders(doc)
+	detection := models.LoginDetection{
+		Methods:      []string{},
+		SSOProviders: providers,
+		Forms:        []models.AuthForm{},
+	}
+
+	doc.Find("form").Each(func(i int, form *goquery.Selection) {
+		if authForm, ok := classifyAuthForm(form, form.Find("input, select, textarea, button")); ok {
+			detection.Forms = append(detection.Forms, authForm)
+		}
+	})
+
+	// Script-driven pages often leave their credential fields outside any form
+	loose := doc.Find("input").FilterFunction(func(i int, s *goquery.Selection) bool {
+		return s.ParentsFiltered("form").Length() == 0
+	})
+	if loose.Filter("[type='password' i]").Length() > 0 {
+		container := loose.Filter("[type='password' i]").First().Parent()
+		if authForm, ok := classifyAuthForm(container, loose.AddSelection(container.Find("button"))); ok {
+			detection.Forms = append(detection.Forms, authForm)
+		}
+	}
+
+	methods := map[string]bool{}
+	hasRegistration := false
+	for _, form := range detection.Forms {
+		if form.Kind == models.AuthFormRegistration {
+			hasRegistration = true
+		}
+		if form.Kind != models.AuthFormLogin {
+			continue
+		}
+		detection.Confidence = math.Max(detection.Confidence, form.Confidence)
+		for _, method := range form.Methods {
+			methods[method] = true
+		}
+	}
+	// Sign-in buttons alone still make a login page. Bare provider links are
+	// shared by sign-up pages, so they only count when nothing says otherwise.
+	if signIn == ssoSignIn || signIn == ssoUnknown && !hasRegistration {
+		methods[models.AuthMethodSSO] = true
+		detection.Confidence = math.Max(detection.Confidence, 0.6)
+	}
+	for method := range methods {
+		detection.Methods = append(detection.Methods, method)
+	}
+	sort.Strings(detection.Methods)
+	detection.Confidence = math.Round(detection.Confidence*100) / 100
+
+	return detection
+}
+
+func (c *CrawlerService) loginDetectionJSON(detection models.LoginDetection) string {
+	if jsonData, err := json.Marshal(detection); err == nil {
+		return string(jsonData)
+	}
+	return "{}"
+}
+
+// classifyAuthForm scores the fields of a form against each kind of
+// authentication form. It reports false for forms with no authentication signals.
+func classifyAuthForm(form *goquery.Selection, fields *goquery.Selection) (models.AuthForm, bool) {
+	candidate := &authCandidate{
+		scores:   map[string]float64{},
+		evidence: map[string][]string{},
+		methods:  map[string]bool{},
+	}
+
+	var passwords, usernames, otps, personal, visibleText int
+	hasCurrent, hasNew := false, false
+	var submitTexts []string
+
+	fields.Each(func(i int, s *goquery.Selection) {
+		fieldType := strings.ToLower(s.AttrOr("type", "text"))
+		autocomplete := strings.ToLower(s.AttrOr("autocomplete", ""))
+		descriptor := normalizeWords(strings.Join([]string{
+			s.AttrOr("name", ""), s.AttrOr("id", ""), s.AttrOr("placeholder", ""),
+			s.AttrOr("aria-label", ""), autocomplete,
+		}, " "))
+
+		if goquery.NodeName(s) == "button" || fieldType == "submit" {
+			submitTexts = append(submitTexts, strings.TrimSpace(s.Text()+" "+s.AttrOr("value", "")))
+			return
+		}
+
+		switch {
+		case fieldType == "password":
+			passwords++
+			hasCurrent = hasCurrent || strings.Contains(autocomplete, "current-password")
+			hasNew = hasNew || strings.Contains(autocomplete, "new-password")
+		case strings.Contains(autocomplete, "one-time-code") || otpField.MatchString(descriptor) && fieldType != "hidden":
+			otps++
+		case fieldType == "checkbox" && rememberField.MatchString(descriptor):
+			candidate.add(models.AuthFormLogin, 0.1, "a remember-me checkbox")
+		case fieldType == "checkbox" && termsField.MatchString(descriptor):
+			candidate.add(models.AuthFormRegistration, 0.2, "a terms or consent checkbox")
+		case fieldType == "hidden" || fieldType == "checkbox" || fieldType == "radio":
+		case fieldType == "email" || strings.Contains(autocomplete, "username") || strings.Contains(autocomplete, "email") || usernameField.MatchString(descriptor):
+			usernames++
+			visibleText++
+		case personalField.MatchString(descriptor) || strings.Contains(autocomplete, "name") || fieldType == "tel":
+			personal++
+			visibleText++
+		default:
+			visibleText++
+		}
+	})
+
+	// What the form calls itself
+	identity := normalizeWords(strings.Join(append(submitTexts,
+		form.AttrOr("id", ""), form.AttrOr("class", ""), form.AttrOr("name", ""),
+		form.AttrOr("action", ""), form.Find("legend, h1, h2, h3, h4").Text(),
+	), " "))
+
+	if passwords > 0 {
+		candidate.methods[models.AuthMethodPassword] = true
+	}
+	if otps > 0 {
+		candidate.methods[models.AuthMethodOTP] = true
+	}
+
+	switch {
+	case passwords == 1 && !hasNew:
+		candidate.add(models.AuthFormLogin, 0.4, "a single password field")
+	case passwords >= 2:
+		candidate.add(models.AuthFormRegistration, 0.3, fmt.Sprintf("%d password fields", passwords))
+		candidate.add(models.AuthFormPasswordChange, 0.3, fmt.Sprintf("%d password fields", passwords))
+	}
+	if hasCurrent {
+		candidate.add(models.AuthFormLogin, 0.3, "autocomplete=current-password")
+	}
+	if hasNew {
+		candidate.add(models.AuthFormRegistration, 0.3, "autocomplete=new-password")
+		if hasCurrent {
+			candidate.add(models.AuthFormPasswordChange, 0.5, "both current and new passwords are asked for")
+		}
+	}
+	if passwords > 0 && usernames == 1 {
+		candidate.add(models.AuthFormLogin, 0.2, "a username or email field next to the password")
+	}
+	if otps > 0 && passwords == 0 {
+		candidate.add(models.AuthFormLogin, 0.5, "a one-time code field")
+	}
+	if personal > 0 {
+		candidate.add(models.AuthFormRegistration, 0.2, "personal details fields")
+	}
+	if visibleText > 3 {
+		candidate.add(models.AuthFormRegistration, 0.1, fmt.Sprintf("%d text fields", visibleText))
+	}
+
+	if matched := loginWords.FindString(identity); matched != "" && passwords+otps > 0 {
+		candidate.add(models.AuthFormLogin, 0.3, fmt.Sprintf("the form is labelled %q", matched))
+	}
+	if matched := registrationWords.FindString(identity); matched != "" && (passwords > 0 || usernames > 0) {
+		candidate.add(models.AuthFormRegistration, 0.4, fmt.Sprintf("the form is labelled %q", matched))
+	}
+	if matched := resetWords.FindString(identity); matched != "" && passwords == 0 && usernames > 0 {
+		candidate.add(models.AuthFormPasswordReset, 0.6, fmt.Sprintf("the form is labelled %q", matched))
+		if usernames == 1 && visibleText == 1 {
+			candidate.add(models.AuthFormPasswordReset, 0.3, "only an email or username is asked for")
+		}
+	}
+	if matched := changeWords.FindString(identity); matched != "" && passwords > 0 {
+		candidate.add(models.AuthFormPasswordChange, 0.4, fmt.Sprintf("the form is labelled %q", matched))
+	}
+
+	kind, score := "", 0.0
+	for _, k := range []string{models.AuthFormLogin, models.AuthFormRegistration, models.AuthFormPasswordReset, models.AuthFormPasswordChange} {
+		if candidate.scores[k] > score {
+			kind, score = k, candidate.scores[k]
+		}
+	}
+	if kind == "" {
+		return models.AuthForm{}, false
+	}
+
+	authForm := models.AuthForm{
+		Kind:     kind,
+		Methods:  []string{},
+		Element:  cssPath(form),
+		Action:   strings.TrimSpace(form.AttrOr("action", "")),
+		Hidden:   isHiddenForm(form),
+		Evidence: candidate.evidence[kind],
+	}
+	for _, method := range []string{models.AuthMethodPassword, models.AuthMethodOTP} {
+		if candidate.methods[method] {
+			authForm.Methods = append(authForm.Methods, method)
+		}
+	}
+	if authForm.Hidden {
+		// Forms in closed modals are less likely to be the page's purpose
+		score *= 0.6
+		authForm.Evidence = append(authForm.Evidence, "the form is hidden until shown by script")
+	}
+	authForm.Confidence = math.Round(math.Min(score, 1)*100) / 100
+
+	return authForm, true
+}
+
+// How single sign-on buttons are worded
+const (
+	ssoNone = iota
+	ssoUnknown
+	ssoSignUp
+	ssoSignIn
+)
+
+// ssoProviders returns the identity providers the page offers buttons or
+// links for, and whether they are worded for signing in or signing up
+func ssoProviders(doc *goquery.Document) ([]string, int) {
+	found := map[string]bool{}
+	wording := ssoNone
+	doc.Find("a, button, input[type='submit' i], input[type='button' i]").Each(func(i int, s *goquery.Selection) {
+		// Visible wording is only lowercased, since splitting camel case
+		// would break provider names such as GitHub and LinkedIn apart
+		text := strings.Join(strings.Fields(strings.ToLower(s.Text()+" "+s.AttrOr("value", "")+" "+s.AttrOr("aria-label", "")+" "+s.AttrOr("title", ""))), " ")
+		if match := ssoButton.FindStringSubmatch(text); match != nil && ssoNames[match[3]] != "" {
+			found[ssoNames[match[3]]] = true
+			if strings.HasPrefix(match[1], "sign") && strings.HasSuffix(match[1], "up") {
+				wording = max(wording, ssoSignUp)
+			} else {
+				wording = ssoSignIn
+			}
+		}
+		href := strings.ToLower(s.AttrOr("href", ""))
+		for endpoint, provider := range ssoEndpoints {
+			if strings.Contains(href, endpoint) {
+				found[provider] = true
+				wording = max(wording, ssoUnknown)
+			}
+		}
+	})
+
+	providers := []string{}
+	for provider := range found {
+		providers = append(providers, provider)
+	}
+	sort.Strings(providers)
+	return providers, wording
+}
+
+// isHiddenForm reports whether a form or one of its ancestors is hidden by
+// markup, as forms in unopened modals are
+func isHiddenForm(form *goquery.Selection) bool {
+	return form.Parents().AddSelection(form).FilterFunction(func(i int, s *goquery.Selection) bool {
+		if _, hidden := s.Attr("hidden"); hidden {
+			return true
+		}
+		style := strings.ReplaceAll(strings.ToLower(s.AttrOr("style", "")), " ", "")
+		return strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden") ||
+			strings.EqualFold(s.AttrOr("aria-hidden", ""), "true")
+	}).Length() > 0
+}
+
+// normalizeWords lowercases text and splits identifiers such as "loginForm"
+// or "sign_in" into words for keyword matching
+func normalizeWords(text string) string {
+	var b strings.Builder
+	for i, r := range text {
+		if r >= 'A' && r <= 'Z' && i > 0 && text[i-1] >= 'a' && text[i-1] <= 'z' {
+			b.WriteByte(' ')
+		}
+		switch r {
+		case '-', '_', '/', '.', '[', ']':
+			b.WriteByte(' ')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return strings.Join(strings.Fields(strings.ToLower(b.String())), " ")
+}
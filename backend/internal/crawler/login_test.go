@@ -0,0 +1,119 @@
+package crawler
+
+import (
+	"reflect"
+	"testing"
+
+	"website-crawler/internal/models"
+)
+
+func TestDetectLogin(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		kinds     []string
+		methods   []string
+		providers []string
+		login     bool
+	}{
+		{
+			"login form",
+			`<form id="loginForm" action="/session"><input type="email" name="email">
+<input type="password" name="password" autocomplete="current-password">
+<input type="checkbox" name="remember_me"><button type="submit">Sign in</button></form>`,
+			[]string{models.AuthFormLogin}, []string{models.AuthMethodPassword}, []string{}, true,
+		},
+		{
+			"registration form",
+			`<form action="/users"><input name="first_name"><input name="last_name"><input type="email" name="email">
+<input type="password" name="password" autocomplete="new-password"><input type="password" name="password_confirmation" autocomplete="new-password">
+<input type="checkbox" name="accept_terms"><button>Create account</button></form>`,
+			[]string{models.AuthFormRegistration}, []string{}, []string{}, false,
+		},
+		{
+			"password reset",
+			`<form action="/password/forgot"><input type="email" name="email"><button>Reset password</button></form>`,
+			[]string{models.AuthFormPasswordReset}, []string{}, []string{}, false,
+		},
+		{
+			"password change",
+			`<form><input type="password" autocomplete="current-password"><input type="password" autocomplete="new-password">
+<button>Change password</button></form>`,
+			[]string{models.AuthFormPasswordChange}, []string{}, []string{}, false,
+		},
+		{
+			"one-time code",
+			`<form><input name="otp" autocomplete="one-time-code" inputmode="numeric"><button>Verify</button></form>`,
+			[]string{models.AuthFormLogin}, []string{models.AuthMethodOTP}, []string{}, true,
+		},
+		{
+			"sign in with providers",
+			`<a href="https://accounts.google.com/o/oauth2/auth">Sign in with Google</a><button>Continue with GitHub</button><a href="/auth">Log-in with LinkedIn</a>`,
+			[]string{}, []string{models.AuthMethodSSO}, []string{"GitHub", "Google", "LinkedIn"}, true,
+		},
+		{
+			"sign up with a provider",
+			`<form><input name="full_name"><input type="email" name="email"><input type="password" autocomplete="new-password">
+<button>Sign up</button></form><a href="https://accounts.google.com/o/oauth2/auth">Google</a>`,
+			[]string{models.AuthFormRegistration}, []string{}, []string{"Google"}, false,
+		},
+		{
+			"search form",
+			`<form action="/search"><input type="search" name="q"><button>Search</button></form>`,
+			[]string{}, []string{}, []string{}, false,
+		},
+		{
+			"fields outside a form",
+			`<div class="login"><input name="username"><input type="password"><button>Log in</button></div>`,
+			[]string{models.AuthFormLogin}, []string{models.AuthMethodPassword}, []string{}, true,
+		},
+	}
+
+	c := NewCrawlerService()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detection := c.detectLogin(newTestDocument(t, `<html><body>`+tt.body+`</body></html>`))
+
+			kinds := []string{}
+			for _, form := range detection.Forms {
+				kinds = append(kinds, form.Kind)
+			}
+			if !reflect.DeepEqual(kinds, tt.kinds) {
+				t.Errorf("form kinds %v, want %v: %+v", kinds, tt.kinds, detection.Forms)
+			}
+			if !reflect.DeepEqual(detection.Methods, tt.methods) || !reflect.DeepEqual(detection.SSOProviders, tt.providers) {
+				t.Errorf("methods %v with providers %v, want %v and %v", detection.Methods, detection.SSOProviders, tt.methods, tt.providers)
+			}
+			if login := detection.Confidence >= loginThreshold; login != tt.login {
+				t.Errorf("confidence %v, want a login page: %v", detection.Confidence, tt.login)
+			}
+		})
+	}
+}
+
+func TestDetectLoginHiddenForm(t *testing.T) {
+	visible := `<form><input type="email" name="email"><input type="password" autocomplete="current-password"><button>Log in</button></form>`
+	hidden := `<div class="modal" style="display: none">` + visible + `</div>`
+
+	c := NewCrawlerService()
+	shown := c.detectLogin(newTestDocument(t, `<html><body>`+visible+`</body></html>`))
+	modal := c.detectLogin(newTestDocument(t, `<html><body>`+hidden+`</body></html>`))
+
+	if len(modal.Forms) != 1 || !modal.Forms[0].Hidden || modal.Forms[0].Confidence >= shown.Forms[0].Confidence {
+		t.Errorf("hidden form %+v should score below the visible one (%v)", modal.Forms, shown.Forms[0].Confidence)
+	}
+}
+
+func TestNormalizeWords(t *testing.T) {
+	for text, want := range map[string]string{
+		"loginForm":           "login form",
+		"user_name":           "user name",
+		"  Sign-In  ":         "sign in",
+		"account[email]":      "account email",
+		"/auth/password.html": "auth password html",
+	} {
+		if got := normalizeWords(text); got != want {
+			t.Errorf("normalizeWords(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package crawler
+
+import (
+	"encoding/json"
+	"net/url"
+
+	"website-crawler/internal/models"
+)
+
+// mixedContentClasses maps resource types to how browsers treat them when
+// they are loaded over http from an https page
+var mixedContentClasses = map[string]string{
+	models.ResourceScript:     models.MixedContentActive,
+	models.ResourceStylesheet: models.MixedContentActive,
+	models.ResourceIframe:     models.MixedContentActive,
+	models.ResourceImage:      models.MixedContentPassive,
+	models.ResourceMedia:      models.MixedContentPassive,
+}
+
+// detectMixedContent returns the subresources and form actions of an https
+// page that use plain http. refs are the page's references as resolved by
+// analyzeLinks, so relative URLs already carry the page's scheme.
+func (c *CrawlerService) detectMixedContent(pageURL *url.URL, refs []resourceRef, forms []models.FormInfo, csp map[string][]string) models.MixedContent {
+	mixed := models.MixedContent{Items: []models.MixedContentItem{}}
+	if pageURL.Scheme != "https" {
+		return mixed
+	}
+	_, mixed.Upgraded = csp["upgrade-insecure-requests"]
+
+	for _, ref := range refs {
+		class, ok := mixedContentClasses[ref.Type]
+		if !ok || ref.URL.Scheme != "http" {
+			continue
+		}
+		if class == models.MixedContentActive {
+			mixed.Active++
+		} else {
+			mixed.Passive++
+		}
+		mixed.Items = append(mixed.Items, models.MixedContentItem{
+			URL:          ref.URL.String(),
+			ResourceType: ref.Type,
+			Class:        class,
+			Location:     ref.Location,
+		})
+	}
+
+	for _, form := range forms {
+		actionURL, err := url.Parse(form.Action)
+		if err != nil || actionURL.Scheme != "http" {
+			continue
+		}
+		mixed.Forms++
+		mixed.Items = append(mixed.Items, models.MixedContentItem{
+			URL:          form.Action,
+			ResourceType: "form",
+			Class:        models.MixedContentForm,
+			Location:     models.LinkLocation{CSSPath: form.Element},
+		})
+	}
+
+	return mixed
+}
+
+func (c *CrawlerService) mixedContentJSON(mixed models.MixedContent) string {
+	if jsonData, err := json.Marshal(mixed); err == nil {
+		return string(jsonData)
+	}
+	return "{}"
+}
@@ -0,0 +1,78 @@
+package crawler
+
+import (
+	"net/url"
+	"testing"
+
+	"website-crawler/internal/models"
+)
+
+func TestDetectMixedContent(t *testing.T) {
+	markup := `<html><head>
+<script src="http://cdn.example.com/app.js"></script>
+<link rel="stylesheet" href="http://cdn.example.com/site.css">
+<link rel="stylesheet" href="//cdn.example.com/relative.css">
+<script src="/local.js"></script>
+</head><body>
+<img src="http://images.example.com/a.png" srcset="https://images.example.com/b.png 2x">
+<video src="http://media.example.com/clip.mp4"></video>
+<iframe src="http://widgets.example.com/embed"></iframe>
+<a href="http://example.com/plain-link">links are not subresources</a>
+<form method="post" action="http://example.com/subscribe"><input name="email"></form>
+<form method="post" action="/search"><input name="q"></form>
+</body></html>`
+
+	tests := []struct {
+		name     string
+		pageURL  string
+		csp      map[string][]string
+		active   int
+		passive  int
+		forms    int
+		upgraded bool
+	}{
+		{"https page", "https://example.com/", nil, 3, 2, 1, false},
+		{"upgraded", "https://example.com/", map[string][]string{"upgrade-insecure-requests": {}}, 3, 2, 1, true},
+		{"http page", "http://example.com/", nil, 0, 0, 0, false},
+	}
+
+	c := NewCrawlerService()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pageURL, _ := url.Parse(tt.pageURL)
+			doc := newTestDocument(t, markup)
+			refs := collectReferences(doc, pageURL, newSourceLocator(markup))
+			forms := c.inventoryForms(doc, pageURL)
+
+			mixed := c.detectMixedContent(pageURL, refs, forms, tt.csp)
+			if mixed.Active != tt.active || mixed.Passive != tt.passive || mixed.Forms != tt.forms || mixed.Upgraded != tt.upgraded {
+				t.Errorf("active %d, passive %d, forms %d, upgraded %v, want %d, %d, %d, %v: %+v",
+					mixed.Active, mixed.Passive, mixed.Forms, mixed.Upgraded, tt.active, tt.passive, tt.forms, tt.upgraded, mixed.Items)
+			}
+			if len(mixed.Items) != tt.active+tt.passive+tt.forms {
+				t.Errorf("%d items, want one per mixed reference", len(mixed.Items))
+			}
+		})
+	}
+}
+
+func TestDetectMixedContentItems(t *testing.T) {
+	markup := "<html><body>\n<img src=\"http://images.example.com/a.png\">\n<form action=\"http://example.com/go\"></form></body></html>"
+	pageURL, _ := url.Parse("https://example.com/")
+	doc := newTestDocument(t, markup)
+
+	c := NewCrawlerService()
+	mixed := c.detectMixedContent(pageURL, collectReferences(doc, pageURL, newSourceLocator(markup)), c.inventoryForms(doc, pageURL), nil)
+	if len(mixed.Items) != 2 {
+		t.Fatalf("got %d items, want 2: %+v", len(mixed.Items), mixed.Items)
+	}
+
+	image, form := mixed.Items[0], mixed.Items[1]
+	if image.URL != "http://images.example.com/a.png" || image.ResourceType != models.ResourceImage ||
+		image.Class != models.MixedContentPassive || image.Location.Line != 2 {
+		t.Errorf("image item %+v", image)
+	}
+	if form.URL != "http://example.com/go" || form.Class != models.MixedContentForm || form.Location.CSSPath != "html > body > form" {
+		t.Errorf("form item %+v", form)
+	}
+}
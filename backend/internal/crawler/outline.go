@@ -0,0 +1,86 @@
+package crawler
+
+import (
+	"encoding/json"
+	"strings"
+
+	"website-crawler/internal/models"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// buildHeadingOutline returns the page's headings as a tree in document
+// order, flagging skipped levels, empty headings and h1 problems
+func (c *CrawlerService) buildHeadingOutline(doc *goquery.Document) models.HeadingOutline {
+	outline := models.HeadingOutline{Headings: []*models.OutlineHeading{}}
+
+	var stack []*models.OutlineHeading
+	previousLevel := 0
+
+	doc.Find("h1, h2, h3, h4, h5, h6").Each(func(i int, s *goquery.Selection) {
+		level := int(goquery.NodeName(s)[1] - '0')
+		heading := &models.OutlineHeading{
+			Order: i + 1,
+			Level: level,
+			Text:  headingText(s),
+		}
+
+		if heading.Text == "" {
+			heading.Empty = true
+			outline.EmptyHeadings++
+		}
+
+		// A heading may go any number of levels up, but only one level down
+		// from the heading before it
+		if previousLevel > 0 && level > previousLevel+1 {
+			heading.SkippedLevel = true
+			outline.SkippedLevels++
+		}
+		previousLevel = level
+
+		if level == 1 {
+			outline.H1Count++
+		}
+		outline.Total++
+
+		for len(stack) > 0 && stack[len(stack)-1].Level >= level {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			outline.Headings = append(outline.Headings, heading)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, heading)
+		}
+		stack = append(stack, heading)
+	})
+
+	outline.MissingH1 = outline.H1Count == 0
+	outline.MultipleH1 = outline.H1Count > 1
+
+	return outline
+}
+
+// headingText returns the visible text of a heading, falling back to the alt
+// text of images for headings that only contain graphics
+func headingText(s *goquery.Selection) string {
+	text := strings.Join(strings.Fields(s.Text()), " ")
+	if text != "" {
+		return text
+	}
+
+	var alts []string
+	s.Find("img[alt]").Each(func(i int, img *goquery.Selection) {
+		if alt := strings.TrimSpace(img.AttrOr("alt", "")); alt != "" {
+			alts = append(alts, alt)
+		}
+	})
+	return strings.Join(alts, " ")
+}
+
+func (c *CrawlerService) headingOutlineJSON(doc *goquery.Document) string {
+	if jsonData, err := json.Marshal(c.buildHeadingOutline(doc)); err == nil {
+		return string(jsonData)
+	}
+	return "{}"
+}
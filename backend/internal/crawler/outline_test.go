@@ -0,0 +1,48 @@
+package crawler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestBuildHeadingOutline(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<html><body>
+<h1>Title</h1>
+<h2>First</h2>
+<h4>Too deep</h4>
+<h2>Second</h2>
+<h3> </h3>
+<h1><img src="logo.png" alt="Logo"></h1>
+</body></html>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outline := NewCrawlerService().buildHeadingOutline(doc)
+
+	if outline.Total != 6 || outline.H1Count != 2 || outline.MissingH1 || !outline.MultipleH1 {
+		t.Errorf("total %d, h1 %d, missing %v, multiple %v", outline.Total, outline.H1Count, outline.MissingH1, outline.MultipleH1)
+	}
+	if outline.SkippedLevels != 1 || outline.EmptyHeadings != 1 {
+		t.Errorf("skipped %d, empty %d, want 1 and 1", outline.SkippedLevels, outline.EmptyHeadings)
+	}
+
+	if len(outline.Headings) != 2 {
+		t.Fatalf("got %d top level headings, want 2", len(outline.Headings))
+	}
+	title := outline.Headings[0]
+	if len(title.Children) != 2 || title.Children[0].Text != "First" || title.Children[1].Text != "Second" {
+		t.Fatalf("unexpected children under the first h1: %+v", title.Children)
+	}
+	if deep := title.Children[0].Children; len(deep) != 1 || !deep[0].SkippedLevel {
+		t.Errorf("the h4 under the first h2 should be flagged as skipping a level: %+v", deep)
+	}
+	if empty := title.Children[1].Children; len(empty) != 1 || !empty[0].Empty {
+		t.Errorf("the blank h3 should be flagged as empty: %+v", empty)
+	}
+	if logo := outline.Headings[1]; logo.Text != "Logo" || logo.Order != 6 {
+		t.Errorf("image heading is %q at %d, want Logo at 6", logo.Text, logo.Order)
+	}
+}
@@ -0,0 +1,25 @@
+package crawler
+
+import "sync"
+
+// forEachIndex calls fn for every index below n on at most workers
+// goroutines, returning once all calls have finished
+func forEachIndex(n, workers int, fn func(index int)) {
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < min(workers, n); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range jobs {
+				fn(index)
+			}
+		}()
+	}
+
+	for index := 0; index < n; index++ {
+		jobs <- index
+	}
+	close(jobs)
+	wg.Wait()
+}
@@ -0,0 +1,175 @@
+package crawler
+
+import (
+	"net/url"
+	"strings"
+
+	"website-crawler/internal/models"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// resourceRef is a single URL reference found in the page
+type resourceRef struct {
+	URL      *url.URL
+	Type     string
+	Location models.LinkLocation
+}
+
+// referenceSelector matches every element that can reference another resource
+const referenceSelector = "a[href], img, script[src], link[href], iframe[src], video, audio, source"
+
+// collectReferences returns every http(s) reference in the page in document
+// order, resolved against baseURL and tagged with its resource type
+func collectReferences(doc *goquery.Document, baseURL *url.URL, locator *sourceLocator) []resourceRef {
+	var refs []resourceRef
+
+	add := func(s *goquery.Selection, raw, resourceType, text string) {
+		// Locate every reference, in document order, so repeated values map to the right lines
+		line := locator.Line(raw)
+
+		refURL, ok := resolveReference(baseURL, raw)
+		if !ok {
+			return
+		}
+
+		rel, _ := s.Attr("rel")
+		target, _ := s.Attr("target")
+		refs = append(refs, resourceRef{
+			URL:  refURL,
+			Type: resourceType,
+			Location: models.LinkLocation{
+				AnchorText: text,
+				CSSPath:    cssPath(s),
+				Rel:        rel,
+				Target:     target,
+				Line:       line,
+			},
+		})
+	}
+
+	addAttr := func(s *goquery.Selection, attr, resourceType, text string) {
+		if value, ok := s.Attr(attr); ok {
+			add(s, value, resourceType, text)
+		}
+	}
+
+	addSrcset := func(s *goquery.Selection, resourceType, text string) {
+		srcset, ok := s.Attr("srcset")
+		if !ok {
+			return
+		}
+		for _, candidate := range parseSrcset(srcset) {
+			add(s, candidate, resourceType, text)
+		}
+	}
+
+	doc.Find(referenceSelector).Each(func(i int, s *goquery.Selection) {
+		switch goquery.NodeName(s) {
+		case "a":
+			addAttr(s, "href", models.ResourceLink, strings.Join(strings.Fields(s.Text()), " "))
+		case "img":
+			alt, _ := s.Attr("alt")
+			addAttr(s, "src", models.ResourceImage, alt)
+			addSrcset(s, models.ResourceImage, alt)
+		case "script":
+			addAttr(s, "src", models.ResourceScript, "")
+		case "link":
+			if hasRelToken(s, "stylesheet") {
+				addAttr(s, "href", models.ResourceStylesheet, "")
+			}
+		case "iframe":
+			title, _ := s.Attr("title")
+			addAttr(s, "src", models.ResourceIframe, title)
+		case "video", "audio":
+			addAttr(s, "src", models.ResourceMedia, "")
+			addAttr(s, "poster", models.ResourceImage, "")
+		case "source":
+			// <source> inside <picture> selects images, elsewhere it feeds <video>/<audio>
+			resourceType := models.ResourceMedia
+			if goquery.NodeName(s.Parent()) == "picture" {
+				resourceType = models.ResourceImage
+			}
+			addAttr(s, "src", resourceType, "")
+			addSrcset(s, resourceType, "")
+		}
+	})
+
+	return refs
+}
+
+// resolveReference resolves a raw attribute value against baseURL, returning
+// false for unparseable values and non-http(s) schemes
+func resolveReference(baseURL *url.URL, raw string) (*url.URL, bool) {
+	refURL, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, false
+	}
+
+	if !refURL.IsAbs() {
+		refURL = baseURL.ResolveReference(refURL)
+	}
+
+	if refURL.Scheme != "http" && refURL.Scheme != "https" {
+		return nil, false
+	}
+	return refURL, true
+}
+
+// parseSrcset returns the candidate URLs of a srcset attribute. It follows
+// the HTML parsing rules, so commas inside a URL do not split it: a candidate
+// is a URL up to whitespace, then descriptors up to the next comma.
+func parseSrcset(srcset string) []string {
+	isSpace := func(b byte) bool { return b == ' ' || b == '\t' || b == '\n' || b == '\f' || b == '\r' }
+
+	var urls []string
+	for pos := 0; pos < len(srcset); {
+		// Skip whitespace and separating commas
+		for pos < len(srcset) && (isSpace(srcset[pos]) || srcset[pos] == ',') {
+			pos++
+		}
+		if pos >= len(srcset) {
+			break
+		}
+
+		start := pos
+		for pos < len(srcset) && !isSpace(srcset[pos]) {
+			pos++
+		}
+		candidate := srcset[start:pos]
+
+		// A URL ending in commas has no descriptors, and the commas separate it from the next candidate
+		if trimmed := strings.TrimRight(candidate, ","); trimmed != candidate {
+			if trimmed != "" {
+				urls = append(urls, trimmed)
+			}
+			continue
+		}
+		urls = append(urls, candidate)
+
+		// Descriptors run to the next comma outside parentheses
+		depth := 0
+		for ; pos < len(srcset); pos++ {
+			switch srcset[pos] {
+			case '(':
+				depth++
+			case ')':
+				depth = max(depth-1, 0)
+			}
+			if srcset[pos] == ',' && depth == 0 {
+				break
+			}
+		}
+	}
+	return urls
+}
+
+func hasRelToken(s *goquery.Selection, token string) bool {
+	rel, _ := s.Attr("rel")
+	for _, value := range strings.Fields(strings.ToLower(rel)) {
+		if value == token {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,34 @@
+package crawler
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSrcset(t *testing.T) {
+	tests := []struct {
+		name   string
+		srcset string
+		want   []string
+	}{
+		{"empty", "", nil},
+		{"single url", "a.jpg", []string{"a.jpg"}},
+		{"descriptors", "a.jpg 1x, b.jpg 2x", []string{"a.jpg", "b.jpg"}},
+		{"widths", "small.jpg 480w,large.jpg 1080w", []string{"small.jpg", "large.jpg"}},
+		{"commas inside urls", "/img,w_100.jpg 100w, /img,w_200.jpg 200w", []string{"/img,w_100.jpg", "/img,w_200.jpg"}},
+		{"data url", "data:image/png;base64,iVBORw0KGgo= 1x, b.png 2x", []string{"data:image/png;base64,iVBORw0KGgo=", "b.png"}},
+		{"trailing comma ends a url", "a.jpg, b.jpg,", []string{"a.jpg", "b.jpg"}},
+		{"no space after a url with a comma", "a.jpg,b.jpg", []string{"a.jpg,b.jpg"}},
+		{"extra whitespace and commas", " \n , a.jpg  1x ,\t b.jpg\n2x ", []string{"a.jpg", "b.jpg"}},
+		{"comma inside parentheses", "a.jpg (future, descriptor) 1x, b.jpg", []string{"a.jpg", "b.jpg"}},
+		{"only commas", ",,,", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseSrcset(tt.srcset); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseSrcset(%q) = %q, want %q", tt.srcset, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package crawler
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"website-crawler/internal/models"
+)
+
+// requestTrace collects httptrace timestamps for a single request
+type requestTrace struct {
+	mutex        sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	firstByte    time.Time
+	reused       bool
+}
+
+func newRequestTrace() *requestTrace {
+	return &requestTrace{start: time.Now()}
+}
+
+// clientTrace returns hooks that record into t. Happy Eyeballs may dial
+// several addresses, so only the first start and last finish are kept.
+func (t *requestTrace) clientTrace() *httptrace.ClientTrace {
+	record := func(target *time.Time, overwrite bool) {
+		t.mutex.Lock()
+		defer t.mutex.Unlock()
+		if overwrite || target.IsZero() {
+			*target = time.Now()
+		}
+	}
+
+	return &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { record(&t.dnsStart, false) },
+		DNSDone:           func(httptrace.DNSDoneInfo) { record(&t.dnsDone, true) },
+		ConnectStart:      func(string, string) { record(&t.connectStart, false) },
+		ConnectDone:       func(string, string, error) { record(&t.connectDone, true) },
+		TLSHandshakeStart: func() { record(&t.tlsStart, false) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { record(&t.tlsDone, true) },
+		GotFirstResponseByte: func() {
+			record(&t.firstByte, true)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mutex.Lock()
+			t.reused = info.Reused
+			t.mutex.Unlock()
+		},
+	}
+}
+
+// timing breaks the request down into phases, ending the download at bodyDone
+func (t *requestTrace) timing(bodyDone time.Time) models.ResponseTiming {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return models.ResponseTiming{
+		DNSMs:          elapsedMs(t.dnsStart, t.dnsDone),
+		ConnectMs:      elapsedMs(t.connectStart, t.connectDone),
+		TLSHandshakeMs: elapsedMs(t.tlsStart, t.tlsDone),
+		TTFBMs:         elapsedMs(t.start, t.firstByte),
+		DownloadMs:     elapsedMs(t.firstByte, bodyDone),
+		TotalMs:        elapsedMs(t.start, bodyDone),
+		ReusedConn:     t.reused,
+	}
+}
+
+func elapsedMs(from, to time.Time) float64 {
+	if from.IsZero() || to.IsZero() || to.Before(from) {
+		return 0
+	}
+	return float64(to.Sub(from).Microseconds()) / 1000
+}
+
+// recordResponse stores the final response's metadata and timing on the analysis
+func (c *CrawlerService) recordResponse(analysis *models.Analysis, fetched *fetchResult, bodySize int, bodyDone time.Time) {
+	resp := fetched.Response
+
+	analysis.StatusCode = resp.StatusCode
+	analysis.ContentType = resp.Header.Get("Content-Type")
+	analysis.ContentLength = resp.ContentLength
+	analysis.BodySize = int64(bodySize)
+	analysis.Server = resp.Header.Get("Server")
+
+	// The transport strips Content-Encoding when it decompresses gzip itself
+	analysis.Compression = resp.Header.Get("Content-Encoding")
+	if resp.Uncompressed {
+		analysis.Compression = "gzip"
+	}
+
+	if timingJSON, err := json.Marshal(fetched.Trace.timing(bodyDone)); err == nil {
+		analysis.Timing = string(timingJSON)
+	}
+}
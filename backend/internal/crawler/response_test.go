@@ -0,0 +1,83 @@
+package crawler
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"website-crawler/internal/models"
+)
+
+func TestCrawlWebsiteResponse(t *testing.T) {
+	page := `<html><head><title>Compressed</title></head><body></body></html>`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Server", "test-server")
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		gz.Write([]byte(page))
+		gz.Close()
+	}))
+	defer server.Close()
+
+	analysis, err := NewCrawlerService().CrawlWebsite(context.Background(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if analysis.StatusCode != http.StatusOK || analysis.ContentType != "text/html; charset=utf-8" || analysis.Server != "test-server" {
+		t.Errorf("status %d, content type %q, server %q", analysis.StatusCode, analysis.ContentType, analysis.Server)
+	}
+	if analysis.Compression != "gzip" || analysis.BodySize != int64(len(page)) {
+		t.Errorf("compression %q with %d body bytes, want gzip with %d", analysis.Compression, analysis.BodySize, len(page))
+	}
+
+	var timing models.ResponseTiming
+	if err := json.Unmarshal([]byte(analysis.Timing), &timing); err != nil {
+		t.Fatalf("timing %q: %v", analysis.Timing, err)
+	}
+	if timing.TotalMs <= 0 || timing.TTFBMs <= 0 || timing.TTFBMs > timing.TotalMs {
+		t.Errorf("implausible timing: %+v", timing)
+	}
+	if timing.TLSHandshakeMs != 0 {
+		t.Errorf("plain http reported a %vms TLS handshake", timing.TLSHandshakeMs)
+	}
+}
+
+func TestRequestTraceTiming(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(ms int) time.Time { return start.Add(time.Duration(ms) * time.Millisecond) }
+
+	trace := &requestTrace{
+		start:        start,
+		dnsStart:     at(1),
+		dnsDone:      at(11),
+		connectStart: at(11),
+		connectDone:  at(31),
+		tlsStart:     at(31),
+		tlsDone:      at(71),
+		firstByte:    at(121),
+	}
+
+	want := models.ResponseTiming{
+		DNSMs:          10,
+		ConnectMs:      20,
+		TLSHandshakeMs: 40,
+		TTFBMs:         121,
+		DownloadMs:     79,
+		TotalMs:        200,
+	}
+	if got := trace.timing(at(200)); got != want {
+		t.Errorf("timing = %+v, want %+v", got, want)
+	}
+
+	// A reused connection skips DNS and connect entirely
+	reused := &requestTrace{start: start, firstByte: at(5), reused: true}
+	if got := reused.timing(at(8)); got.DNSMs != 0 || got.ConnectMs != 0 || got.TTFBMs != 5 || got.DownloadMs != 3 || !got.ReusedConn {
+		t.Errorf("reused timing = %+v", got)
+	}
+}
@@ -0,0 +1,362 @@
+package crawler
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"website-crawler/internal/models"
+)
+
+const (
+	// robotsUserAgent is the product token robots.txt groups are matched against
+	robotsUserAgent = "WebsiteCrawler"
+	// robotsCacheTTL is how long a host's robots.txt is reused before it is fetched again
+	robotsCacheTTL = time.Hour
+	// robotsFailureTTL is how long a failed robots.txt fetch is reused, long
+	// enough to spare a struggling host but short enough to recover quickly
+	robotsFailureTTL = 10 * time.Second
+	// robotsFetchLimit is the most of a robots.txt read, as crawlers must parse at least 500 KiB
+	robotsFetchLimit = 500 << 10
+	// maxCrawlDelay caps the Crawl-delay honored so a site crawl still finishes
+	maxCrawlDelay = 30 * time.Second
+)
+
+// robotsRule is an Allow or Disallow line compiled for matching
+type robotsRule struct {
+	models.RobotsRule
+	pattern *regexp.Regexp
+}
+
+// robotsFile is a host's parsed robots.txt with the rules for our user agent
+type robotsFile struct {
+	info  models.RobotsInfo
+	rules []robotsRule
+	// err is set when robots.txt was unreachable, which means the whole host
+	// is off limits until it can be read
+	err error
+}
+
+// robotsEntry caches one host's robots.txt; ready is closed once it is fetched
+type robotsEntry struct {
+	ready   chan struct{}
+	file    *robotsFile
+	expires time.Time
+}
+
+// robotsCache holds the robots.txt of every host crawled recently
+type robotsCache struct {
+	mutex   sync.Mutex
+	entries map[string]*robotsEntry
+	// turns holds, for hosts with a Crawl-delay, when the next request may start
+	turns map[string]time.Time
+}
+
+func newRobotsCache() *robotsCache {
+	return &robotsCache{entries: map[string]*robotsEntry{}, turns: map[string]time.Time{}}
+}
+
+// robotsFor returns the robots.txt of the URL's host, fetching it at most
+// once per cache period however many crawls ask for it at the same time
+func (c *CrawlerService) robotsFor(ctx context.Context, pageURL *url.URL) *robotsFile {
+	key := strings.ToLower(pageURL.Scheme + "://" + pageURL.Host)
+
+	c.robots.mutex.Lock()
+	entry := c.robots.entries[key]
+	if entry != nil {
+		select {
+		case <-entry.ready:
+			if time.Now().After(entry.expires) {
+				entry = nil
+			}
+		default:
+		}
+	}
+	if entry == nil {
+		entry = &robotsEntry{ready: make(chan struct{})}
+		c.robots.entries[key] = entry
+		c.robots.mutex.Unlock()
+
+		entry.file = c.fetchRobots(ctx, key+"/robots.txt")
+		switch {
+		case ctx.Err() != nil:
+			// A cancelled crawl says nothing about the host, so do not keep the result
+			entry.expires = time.Now()
+		case entry.file.err != nil:
+			entry.expires = time.Now().Add(robotsFailureTTL)
+		default:
+			entry.expires = time.Now().Add(robotsCacheTTL)
+		}
+		close(entry.ready)
+		return entry.file
+	}
+	c.robots.mutex.Unlock()
+
+	select {
+	case <-entry.ready:
+		return entry.file
+	case <-ctx.Done():
+		return &robotsFile{info: models.RobotsInfo{URL: key + "/robots.txt", Error: ctx.Err().Error()}, err: ctx.Err()}
+	}
+}
+
+// fetchRobots downloads and parses a robots.txt. Following RFC 9309, a
+// missing file allows everything and an unreachable one disallows everything,
+// recording the failure in the file's err.
+func (c *CrawlerService) fetchRobots(ctx context.Context, robotsURL string) *robotsFile {
+	file := &robotsFile{info: models.RobotsInfo{URL: robotsURL, Groups: []models.RobotsGroup{}, Sitemaps: []string{}}}
+	fail := func(err error) *robotsFile {
+		file.info.Error = err.Error()
+		file.err = err
+		return file
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, robotsURL, nil)
+	if err != nil {
+		return fail(err)
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; WebsiteCrawler/1.0)")
+
+	resp, err := c.robotsClient.Do(req)
+	if err != nil {
+		return fail(err)
+	}
+	defer resp.Body.Close()
+	file.info.StatusCode = resp.StatusCode
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fail(fmt.Errorf("robots.txt returned status %d", resp.StatusCode))
+	case resp.StatusCode >= 400:
+		// No robots.txt, no restrictions
+		return file
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, robotsFetchLimit))
+	if err != nil {
+		return fail(err)
+	}
+	file.info.Groups, file.info.Sitemaps = parseRobots(data)
+
+	for _, group := range robotsGroupsFor(file.info.Groups, robotsUserAgent) {
+		for _, rule := range group.Rules {
+			file.rules = append(file.rules, robotsRule{RobotsRule: rule, pattern: compileRobotsPattern(rule.Path)})
+		}
+		file.info.CrawlDelay = max(file.info.CrawlDelay, group.CrawlDelay)
+	}
+	return file
+}
+
+// robotsGroupsFor returns the groups that apply to a user agent. Groups
+// naming it replace the "*" group rather than add to it, wherever in a
+// group's User-agent lines it appears.
+func robotsGroupsFor(groups []models.RobotsGroup, userAgent string) []models.RobotsGroup {
+	var own, wildcard []models.RobotsGroup
+	for _, group := range groups {
+		named, wild := false, false
+		for _, agent := range group.UserAgents {
+			named = named || strings.EqualFold(agent, userAgent)
+			wild = wild || agent == "*"
+		}
+		switch {
+		case named:
+			own = append(own, group)
+		case wild:
+			wildcard = append(wildcard, group)
+		}
+	}
+	if len(own) == 0 {
+		return wildcard
+	}
+	return own
+}
+
+// parseRobots reads the groups and sitemap references of a robots.txt.
+// Consecutive User-agent lines share the rules that follow them.
+func parseRobots(data []byte) ([]models.RobotsGroup, []string) {
+	groups := []models.RobotsGroup{}
+	sitemaps := []string{}
+	var current *models.RobotsGroup
+	inRules := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 4096), robotsFetchLimit)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if current == nil || inRules {
+				groups = append(groups, models.RobotsGroup{UserAgents: []string{}, Rules: []models.RobotsRule{}})
+				current = &groups[len(groups)-1]
+				inRules = false
+			}
+			// Only the product token counts, so "WebsiteCrawler/1.0" names WebsiteCrawler
+			agent, _, _ := strings.Cut(value, "/")
+			current.UserAgents = append(current.UserAgents, strings.TrimSpace(agent))
+		case "allow", "disallow":
+			if current == nil {
+				continue
+			}
+			inRules = true
+			// An empty Disallow matches nothing
+			if value != "" {
+				current.Rules = append(current.Rules, models.RobotsRule{Allow: key == "allow", Path: value})
+			}
+		case "crawl-delay":
+			if current == nil {
+				continue
+			}
+			inRules = true
+			if delay, err := strconv.ParseFloat(value, 64); err == nil && delay > 0 {
+				current.CrawlDelay = delay
+			}
+		case "sitemap":
+			// Sitemaps are not tied to any group
+			if value != "" {
+				sitemaps = append(sitemaps, value)
+			}
+		}
+	}
+	return groups, sitemaps
+}
+
+// compileRobotsPattern turns a robots.txt path into a regular expression,
+// where "*" matches any characters and a trailing "$" anchors the end
+func compileRobotsPattern(path string) *regexp.Regexp {
+	anchored := strings.HasSuffix(path, "$")
+	path = strings.TrimSuffix(path, "$")
+
+	parts := strings.Split(path, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	expression := "^" + strings.Join(parts, ".*")
+	if anchored {
+		expression += "$"
+	}
+	return regexp.MustCompile(expression)
+}
+
+// allows reports whether our user agent may fetch the URL, along with the
+// rule that decided it. The longest matching rule wins, and Allow wins a tie.
+func (f *robotsFile) allows(pageURL *url.URL) (bool, string) {
+	if f.err != nil {
+		return false, ""
+	}
+
+	path := pageURL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if path == "/robots.txt" {
+		return true, ""
+	}
+	if pageURL.RawQuery != "" {
+		path += "?" + pageURL.RawQuery
+	}
+
+	var best *robotsRule
+	for i := range f.rules {
+		rule := &f.rules[i]
+		if !rule.pattern.MatchString(path) {
+			continue
+		}
+		if best == nil || len(rule.Path) > len(best.Path) || len(rule.Path) == len(best.Path) && rule.Allow && !best.Allow {
+			best = rule
+		}
+	}
+	if best == nil {
+		return true, ""
+	}
+
+	directive := "Disallow"
+	if best.Allow {
+		directive = "Allow"
+	}
+	return best.Allow, directive + ": " + best.Path
+}
+
+// crawlDelay returns the pause robots.txt asks for between requests
+func (f *robotsFile) crawlDelay() time.Duration {
+	return min(time.Duration(f.info.CrawlDelay*float64(time.Second)), maxCrawlDelay)
+}
+
+// waitCrawlDelay waits for the URL's host to be due another request under its
+// Crawl-delay, returning false if the context ends first. Each caller reserves
+// the next turn before waiting, so concurrent requests to a host, whether
+// pages, link checks or asset fetches, are spaced out rather than sent together.
+func (c *CrawlerService) waitCrawlDelay(ctx context.Context, target *url.URL) bool {
+	delay := c.robotsFor(ctx, target).crawlDelay()
+	if delay <= 0 {
+		return ctx.Err() == nil
+	}
+	key := strings.ToLower(target.Scheme + "://" + target.Host)
+
+	now := time.Now()
+	c.robots.mutex.Lock()
+	for host, next := range c.robots.turns {
+		if next.Before(now) {
+			delete(c.robots.turns, host)
+		}
+	}
+	turn := now
+	if next, ok := c.robots.turns[key]; ok {
+		turn = next
+	}
+	c.robots.turns[key] = turn.Add(delay)
+	c.robots.mutex.Unlock()
+
+	wait := time.Until(turn)
+	if wait <= 0 {
+		return true
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// checkRobots applies the robots.txt of the page's host to the page. ignore
+// records that the URL is set to be crawled whatever robots.txt says. An
+// error means robots.txt could not be fetched, so nothing is known about the page.
+func (c *CrawlerService) checkRobots(ctx context.Context, pageURL *url.URL, ignore bool) (models.RobotsInfo, error) {
+	file := c.robotsFor(ctx, pageURL)
+	info := file.info
+	info.Allowed, info.MatchedRule = file.allows(pageURL)
+	info.Ignored = ignore
+	if file.err != nil {
+		return info, fmt.Errorf("failed to fetch robots.txt: %w", file.err)
+	}
+	return info, nil
+}
+
+func (c *CrawlerService) robotsJSON(info models.RobotsInfo) string {
+	if jsonData, err := json.Marshal(info); err == nil {
+		return string(jsonData)
+	}
+	return "{}"
+}
@@ -0,0 +1,311 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"website-crawler/internal/models"
+)
+
+func TestParseRobots(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		groups   []models.RobotsGroup
+		sitemaps []string
+	}{
+		{
+			name:     "empty",
+			data:     "",
+			groups:   []models.RobotsGroup{},
+			sitemaps: []string{},
+		},
+		{
+			name: "single group",
+			data: "User-agent: *\nDisallow: /private\nAllow: /private/public\n",
+			groups: []models.RobotsGroup{{
+				UserAgents: []string{"*"},
+				Rules: []models.RobotsRule{
+					{Allow: false, Path: "/private"},
+					{Allow: true, Path: "/private/public"},
+				},
+			}},
+			sitemaps: []string{},
+		},
+		{
+			name: "consecutive user agents share a group",
+			data: "User-agent: a\nUser-agent: b\nDisallow: /x\n\nUser-agent: c\nDisallow: /y\n",
+			groups: []models.RobotsGroup{
+				{UserAgents: []string{"a", "b"}, Rules: []models.RobotsRule{{Path: "/x"}}},
+				{UserAgents: []string{"c"}, Rules: []models.RobotsRule{{Path: "/y"}}},
+			},
+			sitemaps: []string{},
+		},
+		{
+			name: "product token only, comments and case",
+			data: "USER-AGENT: WebsiteCrawler/1.0 # ours\ndisallow: /tmp # scratch\n",
+			groups: []models.RobotsGroup{
+				{UserAgents: []string{"WebsiteCrawler"}, Rules: []models.RobotsRule{{Path: "/tmp"}}},
+			},
+			sitemaps: []string{},
+		},
+		{
+			name: "empty disallow matches nothing",
+			data: "User-agent: *\nDisallow:\n",
+			groups: []models.RobotsGroup{
+				{UserAgents: []string{"*"}, Rules: []models.RobotsRule{}},
+			},
+			sitemaps: []string{},
+		},
+		{
+			name: "crawl delay and sitemaps",
+			data: "Sitemap: https://example.com/a.xml\nUser-agent: *\nCrawl-delay: 2.5\nDisallow: /x\nSitemap: https://example.com/b.xml\n",
+			groups: []models.RobotsGroup{
+				{UserAgents: []string{"*"}, Rules: []models.RobotsRule{{Path: "/x"}}, CrawlDelay: 2.5},
+			},
+			sitemaps: []string{"https://example.com/a.xml", "https://example.com/b.xml"},
+		},
+		{
+			name:     "rules before any user agent are ignored",
+			data:     "Disallow: /\nCrawl-delay: 5\n",
+			groups:   []models.RobotsGroup{},
+			sitemaps: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			groups, sitemaps := parseRobots([]byte(tt.data))
+			if !reflect.DeepEqual(groups, tt.groups) {
+				t.Errorf("groups = %+v, want %+v", groups, tt.groups)
+			}
+			if !reflect.DeepEqual(sitemaps, tt.sitemaps) {
+				t.Errorf("sitemaps = %v, want %v", sitemaps, tt.sitemaps)
+			}
+		})
+	}
+}
+
+func TestRobotsGroupsFor(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{"wildcard only", "User-agent: *\nDisallow: /a\n\nUser-agent: Other\nDisallow: /b\n", []string{"/a"}},
+		{"our group replaces the wildcard", "User-agent: *\nDisallow: /a\n\nUser-agent: WebsiteCrawler\nDisallow: /b\n", []string{"/b"}},
+		{"named after the wildcard in one group", "User-agent: *\nUser-agent: WebsiteCrawler\nDisallow: /a\n\nUser-agent: *\nDisallow: /b\n", []string{"/a"}},
+		{"named in several groups", "User-agent: websitecrawler\nDisallow: /a\n\nUser-agent: Other\nUser-agent: WebsiteCrawler\nDisallow: /b\n", []string{"/a", "/b"}},
+		{"no applicable group", "User-agent: Other\nDisallow: /a\n", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			groups, _ := parseRobots([]byte(tt.data))
+			var paths []string
+			for _, group := range robotsGroupsFor(groups, robotsUserAgent) {
+				for _, rule := range group.Rules {
+					paths = append(paths, rule.Path)
+				}
+			}
+			if !reflect.DeepEqual(paths, tt.want) {
+				t.Errorf("rules %v, want %v", paths, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileRobotsPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"/private", "/private", true},
+		{"/private", "/private/page", true},
+		{"/private", "/privateer", true},
+		{"/private", "/public", false},
+		{"/private/", "/private", false},
+		{"/*.php", "/index.php", true},
+		{"/*.php", "/dir/index.php?x=1", true},
+		{"/*.php$", "/index.php", true},
+		{"/*.php$", "/index.php?x=1", false},
+		{"/a*b*c", "/a-x-b-y-c", true},
+		{"/a*b*c", "/a-x-c", false},
+		{"/file.html", "/fileXhtml", false},
+		{"/$", "/", true},
+		{"/$", "/page", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			if got := compileRobotsPattern(tt.pattern).MatchString(tt.path); got != tt.want {
+				t.Errorf("compileRobotsPattern(%q) matches %q = %v, want %v", tt.pattern, tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRobotsFileAllows(t *testing.T) {
+	rules := func(lines ...models.RobotsRule) *robotsFile {
+		file := &robotsFile{}
+		for _, rule := range lines {
+			file.rules = append(file.rules, robotsRule{RobotsRule: rule, pattern: compileRobotsPattern(rule.Path)})
+		}
+		return file
+	}
+	allow := func(path string) models.RobotsRule { return models.RobotsRule{Allow: true, Path: path} }
+	disallow := func(path string) models.RobotsRule { return models.RobotsRule{Path: path} }
+
+	tests := []struct {
+		name    string
+		file    *robotsFile
+		url     string
+		allowed bool
+		rule    string
+	}{
+		{"no rules", rules(), "https://example.com/page", true, ""},
+		{"disallowed", rules(disallow("/private")), "https://example.com/private/page", false, "Disallow: /private"},
+		{"unmatched", rules(disallow("/private")), "https://example.com/public", true, ""},
+		{"longest match wins over order", rules(allow("/private/public"), disallow("/private")), "https://example.com/private/public/page", true, "Allow: /private/public"},
+		{"longer disallow beats shorter allow", rules(allow("/"), disallow("/private")), "https://example.com/private", false, "Disallow: /private"},
+		{"allow wins a tie", rules(disallow("/page"), allow("/page")), "https://example.com/page", true, "Allow: /page"},
+		{"query is matched", rules(disallow("/*?sort=")), "https://example.com/list?sort=asc", false, "Disallow: /*?sort="},
+		{"empty path is the root", rules(disallow("/$")), "https://example.com", false, "Disallow: /$"},
+		{"robots.txt is always allowed", rules(disallow("/")), "https://example.com/robots.txt", true, ""},
+		{"unreadable robots.txt allows nothing", &robotsFile{err: errTooManyRedirects}, "https://example.com/page", false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pageURL, err := url.Parse(tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			allowed, rule := tt.file.allows(pageURL)
+			if allowed != tt.allowed || rule != tt.rule {
+				t.Errorf("allows(%s) = %v, %q, want %v, %q", tt.url, allowed, rule, tt.allowed, tt.rule)
+			}
+		})
+	}
+}
+
+func TestRobotsFileCrawlDelay(t *testing.T) {
+	tests := []struct {
+		delay float64
+		want  time.Duration
+	}{
+		{0, 0},
+		{0.5, 500 * time.Millisecond},
+		{10, 10 * time.Second},
+		{30, maxCrawlDelay},
+		{3600, maxCrawlDelay},
+	}
+
+	for _, tt := range tests {
+		file := &robotsFile{info: models.RobotsInfo{CrawlDelay: tt.delay}}
+		if got := file.crawlDelay(); got != tt.want {
+			t.Errorf("crawlDelay() with Crawl-delay %v = %v, want %v", tt.delay, got, tt.want)
+		}
+	}
+}
+
+func TestCrawlWebsiteRobots(t *testing.T) {
+	var fetched int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			w.Write([]byte("User-agent: *\nDisallow: /private/\n"))
+		case "/private/page":
+			fetched++
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html><head><title>Private</title></head></html>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	c := NewCrawlerService()
+	analysis, err := c.CrawlWebsite(context.Background(), server.URL+"/private/page")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !analysis.RobotsDisallowed || fetched != 0 || analysis.Title != "" {
+		t.Errorf("a disallowed page should be reported without being fetched: disallowed %v, fetched %d times", analysis.RobotsDisallowed, fetched)
+	}
+
+	analysis, err = c.CrawlWebsiteWithOptions(context.Background(), server.URL+"/private/page", PageOptions{IgnoreRobots: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !analysis.RobotsDisallowed || fetched != 1 || analysis.Title != "Private" {
+		t.Errorf("ignoring robots.txt should fetch the page and still flag it: disallowed %v, fetched %d times, title %q", analysis.RobotsDisallowed, fetched, analysis.Title)
+	}
+}
+
+func TestCrawlWebsiteRobotsRedirect(t *testing.T) {
+	var fetched int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			w.Write([]byte("User-agent: *\nDisallow: /private/\n"))
+		case "/moved":
+			http.Redirect(w, r, "/private/page", http.StatusMovedPermanently)
+		case "/private/page":
+			fetched++
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html><head><title>Private</title></head></html>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	c := NewCrawlerService()
+	analysis, err := c.CrawlWebsite(context.Background(), server.URL+"/moved")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !analysis.RobotsDisallowed || fetched != 0 || analysis.RedirectCount != 1 || analysis.Title != "" {
+		t.Errorf("a redirect to a disallowed page should stop there: disallowed %v, fetched %d times, %d redirects", analysis.RobotsDisallowed, fetched, analysis.RedirectCount)
+	}
+
+	analysis, err = c.CrawlWebsiteWithOptions(context.Background(), server.URL+"/moved", PageOptions{IgnoreRobots: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fetched != 1 || analysis.Title != "Private" {
+		t.Errorf("ignoring robots.txt should follow the redirect: fetched %d times, title %q", fetched, analysis.Title)
+	}
+}
+
+func TestCrawlWebsiteRobotsUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><head><title>Page</title></head></html>"))
+	}))
+	defer server.Close()
+
+	c := NewCrawlerService()
+	if _, err := c.CrawlWebsite(context.Background(), server.URL+"/page"); err == nil {
+		t.Error("a page whose robots.txt cannot be read should fail")
+	}
+
+	analysis, err := c.CrawlWebsiteWithOptions(context.Background(), server.URL+"/page", PageOptions{IgnoreRobots: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if analysis.RobotsDisallowed || analysis.Title != "Page" {
+		t.Errorf("ignoring robots.txt should analyze the page without flagging it: disallowed %v, title %q", analysis.RobotsDisallowed, analysis.Title)
+	}
+}
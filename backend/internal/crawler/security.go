@@ -0,0 +1,307 @@
+package crawler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"website-crawler/internal/models"
+)
+
+const (
+	// hstsMinMaxAge is the shortest max-age considered adequate (180 days)
+	hstsMinMaxAge = 15552000
+	// hstsPreloadMaxAge is the max-age required for the HSTS preload list (1 year)
+	hstsPreloadMaxAge = 31536000
+)
+
+var validReferrerPolicies = map[string]bool{
+	"no-referrer":                     true,
+	"no-referrer-when-downgrade":      true,
+	"origin":                          true,
+	"origin-when-cross-origin":        true,
+	"same-origin":                     true,
+	"strict-origin":                   true,
+	"strict-origin-when-cross-origin": true,
+	"unsafe-url":                      true,
+}
+
+func addFinding(audit *models.SecurityHeaderAudit, header, value, severity, message string) {
+	audit.Findings = append(audit.Findings, models.SecurityFinding{
+		Header:   header,
+		Value:    value,
+		Severity: severity,
+		Message:  message,
+	})
+}
+
+// auditSecurityHeaders grades the security-relevant response headers of a page
+func (c *CrawlerService) auditSecurityHeaders(header http.Header, isHTTPS bool) models.SecurityHeaderAudit {
+	audit := &models.SecurityHeaderAudit{Findings: []models.SecurityFinding{}}
+
+	csp := header.Get("Content-Security-Policy")
+	audit.CSP = parseCSP(csp)
+	auditCSP(audit, csp, header.Get("Content-Security-Policy-Report-Only"))
+	auditHSTS(audit, header.Get("Strict-Transport-Security"), isHTTPS)
+	auditFraming(audit, header.Get("X-Frame-Options"))
+	auditContentTypeOptions(audit, header.Get("X-Content-Type-Options"))
+	auditReferrerPolicy(audit, header.Get("Referrer-Policy"))
+	auditPermissionsPolicy(audit, header.Get("Permissions-Policy"), header.Get("Feature-Policy"))
+	auditCrossOriginIsolation(audit, header.Get("Cross-Origin-Opener-Policy"), header.Get("Cross-Origin-Embedder-Policy"))
+
+	audit.Score = 100
+	for _, finding := range audit.Findings {
+		switch finding.Severity {
+		case models.SeverityHigh:
+			audit.Score -= 25
+		case models.SeverityMedium:
+			audit.Score -= 10
+		case models.SeverityLow:
+			audit.Score -= 5
+		}
+	}
+	audit.Score = max(audit.Score, 0)
+	audit.Grade = securityGrade(audit.Score)
+
+	return *audit
+}
+
+func (c *CrawlerService) securityHeadersJSON(audit models.SecurityHeaderAudit) string {
+	if jsonData, err := json.Marshal(audit); err == nil {
+		return string(jsonData)
+	}
+	return "{}"
+}
+
+func securityGrade(score int) string {
+	switch {
+	case score >= 90:
+		return "A"
+	case score >= 80:
+		return "B"
+	case score >= 70:
+		return "C"
+	case score >= 60:
+		return "D"
+	}
+	return "F"
+}
+
+// parseCSP splits a Content-Security-Policy into its directives. Only the
+// first occurrence of a directive counts, as in browsers.
+func parseCSP(policy string) map[string][]string {
+	if strings.TrimSpace(policy) == "" {
+		return nil
+	}
+
+	directives := make(map[string][]string)
+	for _, part := range strings.Split(policy, ";") {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			continue
+		}
+		name := strings.ToLower(fields[0])
+		if _, exists := directives[name]; exists {
+			continue
+		}
+		directives[name] = fields[1:]
+	}
+	return directives
+}
+
+func auditCSP(audit *models.SecurityHeaderAudit, policy, reportOnly string) {
+	const header = "Content-Security-Policy"
+
+	if audit.CSP == nil {
+		if reportOnly != "" {
+			addFinding(audit, header, reportOnly, models.SeverityMedium, "CSP is only deployed in report-only mode and is not enforced")
+		} else {
+			addFinding(audit, header, "", models.SeverityHigh, "No Content-Security-Policy header is set")
+		}
+		return
+	}
+
+	// script-src falls back to default-src when it is not given
+	scriptSources, ok := audit.CSP["script-src"]
+	scriptDirective := "script-src"
+	if !ok {
+		scriptSources, ok = audit.CSP["default-src"]
+		scriptDirective = "default-src"
+	}
+
+	problems := 0
+	if !ok {
+		addFinding(audit, header, policy, models.SeverityMedium, "Neither script-src nor default-src is set, so scripts are unrestricted")
+		problems++
+	} else {
+		hasNonceOrHash := false
+		for _, source := range scriptSources {
+			lower := strings.ToLower(source)
+			if strings.HasPrefix(lower, "'nonce-") || strings.HasPrefix(lower, "'sha256-") ||
+				strings.HasPrefix(lower, "'sha384-") || strings.HasPrefix(lower, "'sha512-") {
+				hasNonceOrHash = true
+			}
+		}
+
+		for _, source := range scriptSources {
+			switch strings.ToLower(source) {
+			case "'unsafe-inline'":
+				// Browsers ignore unsafe-inline when a nonce or hash is present
+				if !hasNonceOrHash {
+					addFinding(audit, header, policy, models.SeverityHigh, scriptDirective+" allows 'unsafe-inline', which defeats XSS protection")
+					problems++
+				}
+			case "'unsafe-eval'":
+				addFinding(audit, header, policy, models.SeverityMedium, scriptDirective+" allows 'unsafe-eval'")
+				problems++
+			case "*", "http:", "https:", "data:":
+				addFinding(audit, header, policy, models.SeverityMedium, scriptDirective+" allows scripts from "+source)
+				problems++
+			}
+		}
+	}
+
+	if _, ok := audit.CSP["object-src"]; !ok {
+		if _, ok := audit.CSP["default-src"]; !ok {
+			addFinding(audit, header, policy, models.SeverityLow, "object-src is not restricted")
+			problems++
+		}
+	}
+
+	if problems == 0 {
+		addFinding(audit, header, policy, models.SeverityPass, "Content-Security-Policy restricts scripts")
+	}
+}
+
+func auditHSTS(audit *models.SecurityHeaderAudit, value string, isHTTPS bool) {
+	const header = "Strict-Transport-Security"
+
+	if !isHTTPS {
+		addFinding(audit, header, value, models.SeverityHigh, "The page is not served over https, so HSTS cannot apply")
+		return
+	}
+	if value == "" {
+		addFinding(audit, header, "", models.SeverityHigh, "No Strict-Transport-Security header is set")
+		return
+	}
+
+	policy := &models.HSTSPolicy{MaxAge: -1}
+	for _, part := range strings.Split(value, ";") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "max-age":
+			if maxAge, err := strconv.ParseInt(strings.Trim(strings.TrimSpace(arg), `"`), 10, 64); err == nil {
+				policy.MaxAge = maxAge
+			}
+		case "includesubdomains":
+			policy.IncludeSubDomains = true
+		case "preload":
+			policy.Preload = true
+		}
+	}
+	policy.PreloadEligible = policy.MaxAge >= hstsPreloadMaxAge && policy.IncludeSubDomains && policy.Preload
+	audit.HSTS = policy
+
+	switch {
+	case policy.MaxAge < 0:
+		addFinding(audit, header, value, models.SeverityHigh, "max-age is missing or invalid, so the header is ignored")
+	case policy.MaxAge == 0:
+		addFinding(audit, header, value, models.SeverityHigh, "max-age=0 disables HSTS")
+	case policy.MaxAge < hstsMinMaxAge:
+		addFinding(audit, header, value, models.SeverityMedium, "max-age is shorter than 180 days")
+	case policy.Preload && !policy.PreloadEligible:
+		addFinding(audit, header, value, models.SeverityLow, "preload is requested but the policy needs max-age of at least one year and includeSubDomains")
+	default:
+		addFinding(audit, header, value, models.SeverityPass, "HSTS is enabled")
+	}
+}
+
+func auditFraming(audit *models.SecurityHeaderAudit, xfo string) {
+	if ancestors, ok := audit.CSP["frame-ancestors"]; ok {
+		addFinding(audit, "Content-Security-Policy", "frame-ancestors "+strings.Join(ancestors, " "), models.SeverityPass, "frame-ancestors controls framing")
+		return
+	}
+
+	const header = "X-Frame-Options"
+	switch strings.ToUpper(strings.TrimSpace(xfo)) {
+	case "":
+		addFinding(audit, header, "", models.SeverityMedium, "Neither X-Frame-Options nor CSP frame-ancestors is set, so the page can be framed")
+	case "DENY", "SAMEORIGIN":
+		addFinding(audit, header, xfo, models.SeverityPass, "Framing is restricted")
+	default:
+		addFinding(audit, header, xfo, models.SeverityMedium, "Unsupported X-Frame-Options value; use DENY, SAMEORIGIN or CSP frame-ancestors")
+	}
+}
+
+func auditContentTypeOptions(audit *models.SecurityHeaderAudit, value string) {
+	const header = "X-Content-Type-Options"
+	switch {
+	case value == "":
+		addFinding(audit, header, "", models.SeverityLow, "No X-Content-Type-Options header is set")
+	case !strings.EqualFold(strings.TrimSpace(value), "nosniff"):
+		addFinding(audit, header, value, models.SeverityLow, "X-Content-Type-Options should be nosniff")
+	default:
+		addFinding(audit, header, value, models.SeverityPass, "MIME sniffing is disabled")
+	}
+}
+
+func auditReferrerPolicy(audit *models.SecurityHeaderAudit, value string) {
+	const header = "Referrer-Policy"
+	if value == "" {
+		addFinding(audit, header, "", models.SeverityLow, "No Referrer-Policy header is set")
+		return
+	}
+
+	// Browsers use the last policy they understand from a comma separated list
+	effective := ""
+	for _, policy := range strings.Split(value, ",") {
+		policy = strings.ToLower(strings.TrimSpace(policy))
+		if validReferrerPolicies[policy] {
+			effective = policy
+		}
+	}
+
+	switch effective {
+	case "":
+		addFinding(audit, header, value, models.SeverityLow, "Referrer-Policy has no recognised value")
+	case "unsafe-url", "no-referrer-when-downgrade":
+		addFinding(audit, header, value, models.SeverityLow, effective+" leaks full URLs to other origins")
+	default:
+		addFinding(audit, header, value, models.SeverityPass, "Referrer information is limited")
+	}
+}
+
+func auditPermissionsPolicy(audit *models.SecurityHeaderAudit, value, featurePolicy string) {
+	const header = "Permissions-Policy"
+	switch {
+	case value != "":
+		addFinding(audit, header, value, models.SeverityPass, "Browser features are restricted")
+	case featurePolicy != "":
+		addFinding(audit, "Feature-Policy", featurePolicy, models.SeverityLow, "Feature-Policy is deprecated; use Permissions-Policy")
+	default:
+		addFinding(audit, header, "", models.SeverityLow, "No Permissions-Policy header is set")
+	}
+}
+
+func auditCrossOriginIsolation(audit *models.SecurityHeaderAudit, coop, coep string) {
+	switch strings.ToLower(strings.TrimSpace(coop)) {
+	case "":
+		addFinding(audit, "Cross-Origin-Opener-Policy", "", models.SeverityLow, "No Cross-Origin-Opener-Policy header is set")
+	case "same-origin", "same-origin-allow-popups", "noopener-allow-popups":
+		addFinding(audit, "Cross-Origin-Opener-Policy", coop, models.SeverityPass, "The browsing context is isolated from cross-origin openers")
+	case "unsafe-none":
+		addFinding(audit, "Cross-Origin-Opener-Policy", coop, models.SeverityLow, "unsafe-none disables opener isolation")
+	default:
+		addFinding(audit, "Cross-Origin-Opener-Policy", coop, models.SeverityLow, "Unrecognised Cross-Origin-Opener-Policy value")
+	}
+
+	switch strings.ToLower(strings.TrimSpace(coep)) {
+	case "":
+		addFinding(audit, "Cross-Origin-Embedder-Policy", "", models.SeverityInfo, "No Cross-Origin-Embedder-Policy header is set; only needed for cross-origin isolation")
+	case "require-corp", "credentialless":
+		addFinding(audit, "Cross-Origin-Embedder-Policy", coep, models.SeverityPass, "Cross-origin resources must opt in to being embedded")
+	default:
+		addFinding(audit, "Cross-Origin-Embedder-Policy", coep, models.SeverityInfo, "Cross-Origin-Embedder-Policy does not enable isolation")
+	}
+}
@@ -0,0 +1,108 @@
+package crawler
+
+import (
+	"net/http"
+	"testing"
+
+	"website-crawler/internal/models"
+)
+
+func TestAuditSecurityHeaders(t *testing.T) {
+	strong := map[string]string{
+		"Content-Security-Policy":      "default-src 'self'; script-src 'self' 'nonce-abc' 'unsafe-inline'; frame-ancestors 'none'",
+		"Strict-Transport-Security":    "max-age=63072000; includeSubDomains; preload",
+		"X-Content-Type-Options":       "nosniff",
+		"Referrer-Policy":              "no-referrer, strict-origin-when-cross-origin",
+		"Permissions-Policy":           "geolocation=()",
+		"Cross-Origin-Opener-Policy":   "same-origin",
+		"Cross-Origin-Embedder-Policy": "require-corp",
+	}
+
+	tests := []struct {
+		name     string
+		headers  map[string]string
+		isHTTPS  bool
+		grade    string
+		score    int
+		severity map[string]string // worst expected severity per header
+	}{
+		{"strong https", strong, true, "A", 100, nil},
+		{"strong over http", strong, false, "C", 75, map[string]string{"Strict-Transport-Security": models.SeverityHigh}},
+		{"nothing set", nil, true, "F", 20, map[string]string{
+			"Content-Security-Policy":   models.SeverityHigh,
+			"Strict-Transport-Security": models.SeverityHigh,
+			"X-Frame-Options":           models.SeverityMedium,
+		}},
+		{"weak values", map[string]string{
+			"Content-Security-Policy":    "script-src 'self' 'unsafe-inline' https:",
+			"Strict-Transport-Security":  "max-age=3600",
+			"X-Frame-Options":            "ALLOW-FROM https://example.com",
+			"X-Content-Type-Options":     "sniff",
+			"Referrer-Policy":            "unsafe-url",
+			"Feature-Policy":             "camera 'none'",
+			"Cross-Origin-Opener-Policy": "unsafe-none",
+		}, true, "F", 20, map[string]string{
+			"Content-Security-Policy":   models.SeverityHigh,
+			"Strict-Transport-Security": models.SeverityMedium,
+			"X-Frame-Options":           models.SeverityMedium,
+			"Feature-Policy":            models.SeverityLow,
+		}},
+	}
+
+	rank := map[string]int{models.SeverityPass: 0, models.SeverityInfo: 1, models.SeverityLow: 2, models.SeverityMedium: 3, models.SeverityHigh: 4}
+
+	c := NewCrawlerService()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			for name, value := range tt.headers {
+				header.Set(name, value)
+			}
+
+			audit := c.auditSecurityHeaders(header, tt.isHTTPS)
+			if audit.Grade != tt.grade || audit.Score != tt.score {
+				t.Errorf("graded %s (%d), want %s (%d): %+v", audit.Grade, audit.Score, tt.grade, tt.score, audit.Findings)
+			}
+
+			worst := map[string]string{}
+			for _, finding := range audit.Findings {
+				if current, ok := worst[finding.Header]; !ok || rank[finding.Severity] > rank[current] {
+					worst[finding.Header] = finding.Severity
+				}
+			}
+			for name, severity := range tt.severity {
+				if worst[name] != severity {
+					t.Errorf("%s: worst finding is %q, want %q", name, worst[name], severity)
+				}
+			}
+		})
+	}
+}
+
+func TestAuditHSTSPolicy(t *testing.T) {
+	tests := []struct {
+		value    string
+		maxAge   int64
+		eligible bool
+		severity string
+	}{
+		{"max-age=63072000; includeSubDomains; preload", 63072000, true, models.SeverityPass},
+		{`max-age="31536000"; includesubdomains`, 31536000, false, models.SeverityPass},
+		{"max-age=31536000; preload", 31536000, false, models.SeverityLow},
+		{"max-age=0", 0, false, models.SeverityHigh},
+		{"includeSubDomains", -1, false, models.SeverityHigh},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			audit := &models.SecurityHeaderAudit{}
+			auditHSTS(audit, tt.value, true)
+			if audit.HSTS == nil || audit.HSTS.MaxAge != tt.maxAge || audit.HSTS.PreloadEligible != tt.eligible {
+				t.Errorf("parsed %+v, want max-age %d and eligible %v", audit.HSTS, tt.maxAge, tt.eligible)
+			}
+			if len(audit.Findings) != 1 || audit.Findings[0].Severity != tt.severity {
+				t.Errorf("findings %+v, want one %s finding", audit.Findings, tt.severity)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package crawler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"website-crawler/internal/models"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Recommended lengths, in characters, for titles and meta descriptions
+const (
+	minTitleLength       = 30
+	maxTitleLength       = 60
+	minDescriptionLength = 70
+	maxDescriptionLength = 160
+)
+
+// auditSEO extracts the page's search and social metadata and flags problems
+// with it. Monitored pages are expected to be indexable.
+func (c *CrawlerService) auditSEO(doc *goquery.Document, header http.Header, pageURL *url.URL) models.SEOAudit {
+	seo := models.SEOAudit{
+		OpenGraph:   map[string]string{},
+		TwitterCard: map[string]string{},
+		Hreflang:    []models.HreflangAlternate{},
+		Issues:      []models.SEOIssue{},
+	}
+	addIssue := func(code, severity, message string) {
+		seo.Issues = append(seo.Issues, models.SEOIssue{Code: code, Severity: severity, Message: message})
+	}
+
+	// Title
+	titles := doc.Find("head title")
+	if titles.Length() == 0 {
+		titles = doc.Find("title")
+	}
+	seo.Title = strings.TrimSpace(titles.First().Text())
+	seo.TitleLength = utf8.RuneCountInString(seo.Title)
+	switch {
+	case seo.Title == "":
+		addIssue("title_missing", models.SeverityHigh, "The page has no title")
+	case seo.TitleLength < minTitleLength:
+		addIssue("title_too_short", models.SeverityLow, fmt.Sprintf("The title is %d characters; aim for %d-%d", seo.TitleLength, minTitleLength, maxTitleLength))
+	case seo.TitleLength > maxTitleLength:
+		addIssue("title_too_long", models.SeverityLow, fmt.Sprintf("The title is %d characters and may be truncated; aim for %d-%d", seo.TitleLength, minTitleLength, maxTitleLength))
+	}
+	if titles.Length() > 1 {
+		addIssue("title_duplicate", models.SeverityMedium, fmt.Sprintf("The page has %d title elements", titles.Length()))
+	}
+
+	// Meta description
+	descriptions := metaByName(doc, "description")
+	if len(descriptions) > 0 {
+		seo.MetaDescription = descriptions[0]
+	}
+	seo.DescriptionLength = utf8.RuneCountInString(seo.MetaDescription)
+	switch {
+	case seo.MetaDescription == "":
+		addIssue("description_missing", models.SeverityMedium, "The page has no meta description")
+	case seo.DescriptionLength < minDescriptionLength:
+		addIssue("description_too_short", models.SeverityLow, fmt.Sprintf("The meta description is %d characters; aim for %d-%d", seo.DescriptionLength, minDescriptionLength, maxDescriptionLength))
+	case seo.DescriptionLength > maxDescriptionLength:
+		addIssue("description_too_long", models.SeverityLow, fmt.Sprintf("The meta description is %d characters and may be truncated; aim for %d-%d", seo.DescriptionLength, minDescriptionLength, maxDescriptionLength))
+	}
+	if len(descriptions) > 1 {
+		addIssue("description_duplicate", models.SeverityMedium, fmt.Sprintf("The page has %d meta descriptions", len(descriptions)))
+	}
+
+	// Canonical
+	var canonicals []string
+	doc.Find("link[href]").Each(func(i int, s *goquery.Selection) {
+		if hasRelToken(s, "canonical") {
+			canonicals = append(canonicals, s.AttrOr("href", ""))
+		}
+	})
+	switch {
+	case len(canonicals) == 0:
+		addIssue("canonical_missing", models.SeverityLow, "The page has no canonical link")
+	case len(canonicals) > 1:
+		addIssue("canonical_duplicate", models.SeverityMedium, fmt.Sprintf("The page has %d canonical links; search engines may ignore them all", len(canonicals)))
+	}
+	if len(canonicals) > 0 {
+		if canonicalURL, ok := resolveReference(pageURL, canonicals[0]); ok {
+			seo.Canonical = canonicalURL.String()
+			seo.CanonicalIsSelf = normalizePageURL(canonicalURL) == normalizePageURL(pageURL)
+			if !seo.CanonicalIsSelf {
+				addIssue("canonical_elsewhere", models.SeverityMedium, "The canonical link points to "+seo.Canonical+", so this page may not be indexed")
+			}
+		} else {
+			addIssue("canonical_invalid", models.SeverityMedium, "The canonical link is not a valid http(s) URL")
+		}
+	}
+
+	// Robots directives
+	robots := metaByName(doc, "robots")
+	seo.MetaRobots = strings.Join(robots, ", ")
+	seo.XRobotsTag = strings.Join(header.Values("X-Robots-Tag"), ", ")
+	seo.Indexable = !hasRobotsDirective(seo.MetaRobots, "noindex") && !hasRobotsDirective(seo.XRobotsTag, "noindex")
+	if !seo.Indexable {
+		addIssue("noindex", models.SeverityHigh, "The page is marked noindex and will be dropped from search results")
+	}
+	if hasRobotsDirective(seo.MetaRobots, "nofollow") || hasRobotsDirective(seo.XRobotsTag, "nofollow") {
+		addIssue("nofollow", models.SeverityLow, "The page is marked nofollow, so its links pass no ranking signals")
+	}
+	if len(robots) > 1 {
+		addIssue("robots_duplicate", models.SeverityLow, fmt.Sprintf("The page has %d meta robots tags", len(robots)))
+	}
+
+	// Hreflang alternates
+	seenLangs := map[string]bool{}
+	hasSelf := false
+	doc.Find("link[hreflang][href]").Each(func(i int, s *goquery.Selection) {
+		if !hasRelToken(s, "alternate") {
+			return
+		}
+		lang := strings.ToLower(strings.TrimSpace(s.AttrOr("hreflang", "")))
+		alternateURL, ok := resolveReference(pageURL, s.AttrOr("href", ""))
+		if !ok {
+			addIssue("hreflang_invalid", models.SeverityMedium, "The hreflang alternate for "+lang+" is not a valid http(s) URL")
+			return
+		}
+		if seenLangs[lang] {
+			addIssue("hreflang_duplicate", models.SeverityMedium, "More than one alternate is given for hreflang "+lang)
+		}
+		seenLangs[lang] = true
+		if normalizePageURL(alternateURL) == normalizePageURL(pageURL) {
+			hasSelf = true
+		}
+		seo.Hreflang = append(seo.Hreflang, models.HreflangAlternate{Lang: lang, URL: alternateURL.String()})
+	})
+	if len(seo.Hreflang) > 0 && !hasSelf {
+		addIssue("hreflang_no_self", models.SeverityLow, "The hreflang alternates do not include this page")
+	}
+
+	// Social tags
+	duplicateSocial := map[string]bool{}
+	doc.Find("meta[property], meta[name]").Each(func(i int, s *goquery.Selection) {
+		key := strings.ToLower(strings.TrimSpace(s.AttrOr("property", s.AttrOr("name", ""))))
+		content := strings.TrimSpace(s.AttrOr("content", ""))
+
+		var tags map[string]string
+		switch {
+		case strings.HasPrefix(key, "og:"):
+			tags = seo.OpenGraph
+		case strings.HasPrefix(key, "twitter:"):
+			tags = seo.TwitterCard
+		default:
+			return
+		}
+
+		// og:image and friends may legitimately repeat, so only the basics are checked
+		if _, exists := tags[key]; exists {
+			if !duplicateSocial[key] && (key == "og:title" || key == "og:description" || key == "og:url" || key == "twitter:card") {
+				addIssue("social_duplicate", models.SeverityLow, "The "+key+" tag is set more than once")
+			}
+			duplicateSocial[key] = true
+			return
+		}
+		tags[key] = content
+	})
+	for _, required := range []string{"og:title", "og:description", "og:image"} {
+		if seo.OpenGraph[required] == "" {
+			addIssue("open_graph_missing", models.SeverityLow, "The "+required+" tag is missing")
+		}
+	}
+	if seo.TwitterCard["twitter:card"] == "" {
+		addIssue("twitter_card_missing", models.SeverityInfo, "The twitter:card tag is missing")
+	}
+
+	// Viewport
+	viewports := metaByName(doc, "viewport")
+	if len(viewports) > 0 {
+		seo.Viewport = viewports[0]
+	}
+	switch {
+	case len(viewports) == 0:
+		addIssue("viewport_missing", models.SeverityMedium, "The page has no viewport meta tag and will not render well on mobile")
+	case len(viewports) > 1:
+		addIssue("viewport_duplicate", models.SeverityLow, fmt.Sprintf("The page has %d viewport meta tags", len(viewports)))
+	}
+
+	return seo
+}
+
+func (c *CrawlerService) seoJSON(seo models.SEOAudit) string {
+	if jsonData, err := json.Marshal(seo); err == nil {
+		return string(jsonData)
+	}
+	return "{}"
+}
+
+// metaByName returns the content of every <meta name=...> matching name
+func metaByName(doc *goquery.Document, name string) []string {
+	var values []string
+	doc.Find("meta[name]").Each(func(i int, s *goquery.Selection) {
+		if strings.EqualFold(strings.TrimSpace(s.AttrOr("name", "")), name) {
+			values = append(values, strings.TrimSpace(s.AttrOr("content", "")))
+		}
+	})
+	return values
+}
+
+// hasRobotsDirective reports whether a robots value carries directive, either
+// directly or through "none", which means noindex and nofollow
+func hasRobotsDirective(value, directive string) bool {
+	for _, part := range strings.Split(strings.ToLower(value), ",") {
+		part = strings.TrimSpace(part)
+		// X-Robots-Tag may scope directives to a crawler, as in "googlebot: noindex"
+		if _, scoped, ok := strings.Cut(part, ":"); ok {
+			part = strings.TrimSpace(scoped)
+		}
+		if part == directive || part == "none" {
+			return true
+		}
+	}
+	return false
+}
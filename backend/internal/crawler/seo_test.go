@@ -0,0 +1,119 @@
+package crawler
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// newTestDocument parses markup for tests that audit a document
+func newTestDocument(t *testing.T, markup string) *goquery.Document {
+	t.Helper()
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(markup))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestAuditSEO(t *testing.T) {
+	pageURL, _ := url.Parse("https://example.com/page")
+
+	tests := []struct {
+		name   string
+		markup string
+		header http.Header
+		issues []string
+	}{
+		{
+			"complete",
+			`<html><head>
+<title>A descriptive page title of a sensible length</title>
+<meta name="description" content="A meta description that is long enough to be useful in search results, but not too long.">
+<link rel="canonical" href="/page#top">
+<link rel="alternate" hreflang="en" href="https://example.com/page">
+<link rel="alternate" hreflang="de" href="https://example.com/de/page">
+<meta property="og:title" content="Title"><meta property="og:description" content="Description">
+<meta property="og:image" content="https://example.com/a.png"><meta property="og:image" content="https://example.com/b.png">
+<meta name="twitter:card" content="summary">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+</head></html>`,
+			nil,
+			nil,
+		},
+		{
+			"empty",
+			`<html><head></head><body></body></html>`,
+			nil,
+			[]string{"title_missing", "description_missing", "canonical_missing", "open_graph_missing", "open_graph_missing", "open_graph_missing", "twitter_card_missing", "viewport_missing"},
+		},
+		{
+			"conflicting",
+			`<html><head>
+<title>Short</title><title>Another</title>
+<meta name="description" content="Too short">
+<link rel="canonical" href="https://example.com/other"><link rel="canonical" href="https://example.com/page">
+<meta name="robots" content="index, nofollow">
+<link rel="alternate" hreflang="fr" href="https://example.com/fr"><link rel="alternate" hreflang="FR" href="https://example.com/fr2">
+<meta property="og:title" content="A"><meta property="og:title" content="B">
+<meta property="og:description" content="D"><meta property="og:image" content="I">
+<meta name="twitter:card" content="summary">
+<meta name="viewport" content="width=device-width"><meta name="viewport" content="width=device-width">
+</head></html>`,
+			http.Header{"X-Robots-Tag": {"noindex"}},
+			[]string{"title_too_short", "title_duplicate", "description_too_short", "canonical_duplicate", "canonical_elsewhere",
+				"noindex", "nofollow", "hreflang_duplicate", "hreflang_no_self", "social_duplicate", "viewport_duplicate"},
+		},
+	}
+
+	c := NewCrawlerService()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := tt.header
+			if header == nil {
+				header = http.Header{}
+			}
+			seo := c.auditSEO(newTestDocument(t, tt.markup), header, pageURL)
+
+			var codes []string
+			for _, issue := range seo.Issues {
+				codes = append(codes, issue.Code)
+			}
+			sort.Strings(codes)
+			want := append([]string(nil), tt.issues...)
+			sort.Strings(want)
+			if !reflect.DeepEqual(codes, want) {
+				t.Errorf("issues %v, want %v", codes, want)
+			}
+		})
+	}
+}
+
+func TestAuditSEOMetadata(t *testing.T) {
+	pageURL, _ := url.Parse("https://example.com/page")
+	doc := newTestDocument(t, `<html><head>
+<title>  Café menu  </title>
+<link rel="canonical" href="/page">
+<meta name="robots" content="noarchive"><meta name="ROBOTS" content="NOINDEX">
+<link rel="alternate" hreflang="EN-gb" href="/uk/page">
+</head></html>`)
+
+	seo := NewCrawlerService().auditSEO(doc, http.Header{}, pageURL)
+	if seo.Title != "Café menu" || seo.TitleLength != 9 {
+		t.Errorf("title %q of length %d", seo.Title, seo.TitleLength)
+	}
+	if seo.Canonical != "https://example.com/page" || !seo.CanonicalIsSelf {
+		t.Errorf("canonical %q, self %v", seo.Canonical, seo.CanonicalIsSelf)
+	}
+	if seo.Indexable || seo.MetaRobots != "noarchive, NOINDEX" {
+		t.Errorf("robots %q left the page indexable", seo.MetaRobots)
+	}
+	if len(seo.Hreflang) != 1 || seo.Hreflang[0].Lang != "en-gb" || seo.Hreflang[0].URL != "https://example.com/uk/page" {
+		t.Errorf("hreflang %+v", seo.Hreflang)
+	}
+}
@@ -0,0 +1,160 @@
+package crawler
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"website-crawler/internal/models"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const (
+	// DefaultMaxDepth is the default link depth followed in site crawl mode
+	DefaultMaxDepth = 2
+	// DefaultMaxPages is the default number of pages analyzed in site crawl mode
+	DefaultMaxPages = 50
+)
+
+// SiteCrawlOptions configures a multi-page site crawl
+type SiteCrawlOptions struct {
+	MaxDepth int
+	MaxPages int
+	PageOptions
+}
+
+// PageResult holds the outcome of crawling a single page during a site crawl
+type PageResult struct {
+	URL      string
+	Depth    int
+	Analysis *models.Analysis
+	Err      error
+}
+
+// CrawlSite crawls a website breadth-first starting at startURL, following
+// internal links until MaxDepth or MaxPages is reached. onPage, when not nil,
+// is called as each page finishes so callers can persist results as they come.
+func (c *CrawlerService) CrawlSite(ctx context.Context, startURL string, opts SiteCrawlOptions, onPage func(PageResult)) ([]PageResult, error) {
+	if opts.MaxDepth < 0 {
+		opts.MaxDepth = DefaultMaxDepth
+	}
+	if opts.MaxPages <= 0 {
+		opts.MaxPages = DefaultMaxPages
+	}
+
+	start, err := url.Parse(startURL)
+	if err != nil {
+		return nil, err
+	}
+
+	type queued struct {
+		url   string
+		depth int
+	}
+
+	startKey := normalizePageURL(start)
+	seen := map[string]bool{startKey: true}
+	queue := []queued{{url: startKey, depth: 0}}
+	var results []PageResult
+	// Redirects may land a page on another host, but only the start host is crawled
+	startHost := strings.ToLower(start.Hostname())
+	technologies := compileTechnologies(opts.Technologies)
+
+	for len(queue) > 0 && len(results) < opts.MaxPages {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+
+		current := queue[0]
+		queue = queue[1:]
+
+		analysis, links, err := c.crawlPage(ctx, current.url, opts.PageOptions, technologies)
+		result := PageResult{
+			URL:      current.url,
+			Depth:    current.depth,
+			Analysis: analysis,
+			Err:      err,
+		}
+		results = append(results, result)
+		if onPage != nil {
+			onPage(result)
+		}
+
+		if err != nil || current.depth >= opts.MaxDepth {
+			continue
+		}
+
+		for _, link := range links {
+			if seen[link] {
+				continue
+			}
+			if linkURL, err := url.Parse(link); err != nil || linkURL.Hostname() != startHost {
+				continue
+			}
+			seen[link] = true
+			if !opts.IgnoreRobots && !c.robotsAllow(ctx, link) {
+				continue
+			}
+			queue = append(queue, queued{url: link, depth: current.depth + 1})
+		}
+	}
+
+	return results, nil
+}
+
+// robotsAllow reports whether robots.txt lets our user agent fetch a page.
+// Pages whose robots.txt could not be read are kept, so the crawl reports the
+// failure for them instead of dropping them unseen.
+func (c *CrawlerService) robotsAllow(ctx context.Context, pageURL string) bool {
+	parsed, err := url.Parse(pageURL)
+	if err != nil {
+		return false
+	}
+	file := c.robotsFor(ctx, parsed)
+	if file.err != nil {
+		return true
+	}
+	allowed, _ := file.allows(parsed)
+	return allowed
+}
+
+// extractPageLinks returns the unique, normalized internal page links in a document
+func (c *CrawlerService) extractPageLinks(doc *goquery.Document, baseURL *url.URL) []string {
+	var links []string
+	seen := make(map[string]bool)
+
+	doc.Find("a[href]").Each(func(i int, s *goquery.Selection) {
+		href, _ := s.Attr("href")
+		linkURL, ok := resolveReference(baseURL, href)
+		if !ok {
+			return
+		}
+
+		if linkURL.Hostname() != baseURL.Hostname() {
+			return
+		}
+
+		normalized := normalizePageURL(linkURL)
+		if seen[normalized] {
+			return
+		}
+		seen[normalized] = true
+		links = append(links, normalized)
+	})
+
+	return links
+}
+
+// normalizePageURL strips fragments and lowercases the host so the same page
+// is not crawled twice under different spellings
+func normalizePageURL(u *url.URL) string {
+	normalized := *u
+	normalized.Fragment = ""
+	normalized.RawFragment = ""
+	normalized.Host = strings.ToLower(normalized.Host)
+	if normalized.Path == "" {
+		normalized.Path = "/"
+	}
+	return normalized.String()
+}
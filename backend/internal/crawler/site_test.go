@@ -0,0 +1,186 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newSiteServer serves a small site whose pages link to the given paths
+func newSiteServer(t *testing.T, pages map[string][]string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		links, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(w, "<html><head><title>%s</title></head><body>", r.URL.Path)
+		for _, link := range links {
+			fmt.Fprintf(w, `<a href="%s">link</a>`, link)
+		}
+		fmt.Fprint(w, "</body></html>")
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCrawlSite(t *testing.T) {
+	// The other site shares the address but not the host name
+	other := newSiteServer(t, map[string][]string{"/elsewhere": nil})
+	otherURL := strings.Replace(other.URL, "127.0.0.1", "localhost", 1)
+
+	server := newSiteServer(t, map[string][]string{
+		"/":  {"/a", "/b", "/a#section", otherURL + "/elsewhere", "mailto:someone@example.com"},
+		"/a": {"/c", "/"},
+		"/b": {"/c"},
+		"/c": {"/d"},
+		"/d": nil,
+	})
+
+	tests := []struct {
+		name     string
+		maxDepth int
+		maxPages int
+		want     []string
+		depths   []int
+	}{
+		{"start page only", 0, 10, []string{"/"}, []int{0}},
+		{"one level", 1, 10, []string{"/", "/a", "/b"}, []int{0, 1, 1}},
+		{"two levels", 2, 10, []string{"/", "/a", "/b", "/c"}, []int{0, 1, 1, 2}},
+		{"page limit", 5, 2, []string{"/", "/a"}, []int{0, 1}},
+		{"whole site", 5, 10, []string{"/", "/a", "/b", "/c", "/d"}, []int{0, 1, 1, 2, 3}},
+	}
+
+	c := NewCrawlerService()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reported []string
+			results, err := c.CrawlSite(context.Background(), server.URL, SiteCrawlOptions{MaxDepth: tt.maxDepth, MaxPages: tt.maxPages}, func(result PageResult) {
+				reported = append(reported, result.URL)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var paths []string
+			var depths []int
+			for _, result := range results {
+				if result.Err != nil {
+					t.Errorf("%s: %v", result.URL, result.Err)
+					continue
+				}
+				if want := strings.TrimPrefix(result.URL, server.URL); result.Analysis.Title != want {
+					t.Errorf("%s has title %q", result.URL, result.Analysis.Title)
+				}
+				paths = append(paths, strings.TrimPrefix(result.URL, server.URL))
+				depths = append(depths, result.Depth)
+			}
+			if !reflect.DeepEqual(paths, tt.want) {
+				t.Errorf("crawled %v, want %v", paths, tt.want)
+			}
+			if !reflect.DeepEqual(depths, tt.depths) {
+				t.Errorf("depths %v, want %v", depths, tt.depths)
+			}
+			if len(reported) != len(results) {
+				t.Errorf("onPage saw %d pages, want %d", len(reported), len(results))
+			}
+		})
+	}
+}
+
+func TestCrawlSiteStaysOnStartHost(t *testing.T) {
+	other := newSiteServer(t, map[string][]string{"/landing": {"/next"}, "/next": nil})
+	otherURL := strings.Replace(other.URL, "127.0.0.1", "localhost", 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, otherURL+"/landing", http.StatusFound)
+	}))
+	defer server.Close()
+
+	results, err := NewCrawlerService().CrawlSite(context.Background(), server.URL, SiteCrawlOptions{MaxDepth: 5, MaxPages: 10}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Err != nil || results[0].Analysis.FinalURL != otherURL+"/landing" {
+		t.Fatalf("want only the start page, redirected off the site: %+v", results)
+	}
+}
+
+func TestCrawlSiteCrawlDelay(t *testing.T) {
+	const delay = 100 * time.Millisecond
+	var mutex sync.Mutex
+	var times []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			fmt.Fprintf(w, "User-agent: *\nCrawl-delay: %g\n", delay.Seconds())
+			return
+		}
+		mutex.Lock()
+		times = append(times, time.Now())
+		mutex.Unlock()
+
+		switch r.URL.Path {
+		case "/", "/a":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body><a href="/a">a</a><img src="/logo.png"></body></html>`)
+		case "/logo.png":
+			// Streamed, so the asset inventory fetches it too
+			w.(http.Flusher).Flush()
+			w.Write([]byte("png"))
+		}
+	}))
+	defer server.Close()
+
+	results, err := NewCrawlerService().CrawlSite(context.Background(), server.URL, SiteCrawlOptions{MaxDepth: 1, MaxPages: 10}, nil)
+	if err != nil || len(results) != 2 {
+		t.Fatalf("crawled %d pages: %v", len(results), err)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	// Pages, link checks and asset fetches all take turns
+	if len(times) < 6 {
+		t.Fatalf("the host got %d requests, want pages, link checks and asset fetches", len(times))
+	}
+	for i := 1; i < len(times); i++ {
+		if gap := times[i].Sub(times[i-1]); gap < delay/2 {
+			// Half the delay leaves room for scheduling noise while still catching a burst
+			t.Errorf("request %d came %v after the one before, want about the Crawl-delay of %v", i, gap, delay)
+		}
+	}
+}
+
+func TestNormalizePageURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://Example.COM", "https://example.com/"},
+		{"https://example.com/page#top", "https://example.com/page"},
+		{"https://example.com/page?q=1#top", "https://example.com/page?q=1"},
+		{"http://example.com:8080/Path", "http://example.com:8080/Path"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := normalizePageURL(u); got != tt.want {
+				t.Errorf("normalizePageURL(%s) = %s, want %s", tt.url, got, tt.want)
+			}
+		})
+	}
+}
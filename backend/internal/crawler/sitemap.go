@@ -0,0 +1,379 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"website-crawler/internal/models"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const (
+	// sitemapSizeLimit is the largest uncompressed sitemap the protocol allows
+	sitemapSizeLimit = 50 << 20
+	// sitemapMaxURLs is the most URLs a single sitemap may list
+	sitemapMaxURLs = 50000
+	// sitemapMaxFiles bounds the sitemaps read for one site, index files included
+	sitemapMaxFiles = 50
+)
+
+// lastModLayouts are the W3C Datetime forms a <lastmod> may take. Fractional
+// seconds are accepted by the layout with seconds.
+var lastModLayouts = []string{
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02",
+	"2006-01",
+	"2006",
+}
+
+// sitemapDocument is a <urlset> or a <sitemapindex>
+type sitemapDocument struct {
+	XMLName  xml.Name
+	URLs     []sitemapEntry `xml:"url"`
+	Sitemaps []sitemapEntry `xml:"sitemap"`
+}
+
+type sitemapEntry struct {
+	Loc        string `xml:"loc"`
+	LastMod    string `xml:"lastmod"`
+	ChangeFreq string `xml:"changefreq"`
+	Priority   string `xml:"priority"`
+}
+
+// ReadSitemaps reads the sitemaps of a site and checks the URLs they list.
+// target is either the site, whose sitemaps are taken from robots.txt or
+// /sitemap.xml, or a sitemap itself. Index files are followed and gzipped
+// sitemaps unpacked. keep, when not nil, selects the URLs reported.
+func (c *CrawlerService) ReadSitemaps(ctx context.Context, target string, keep func(loc string) bool) (models.SitemapReport, error) {
+	report := models.SitemapReport{
+		Sitemaps: []models.SitemapFile{},
+		URLs:     []models.SitemapURL{},
+		Errors:   []models.SitemapError{},
+	}
+
+	targetURL, err := url.Parse(target)
+	if err != nil || targetURL.Host == "" || (targetURL.Scheme != "http" && targetURL.Scheme != "https") {
+		return report, fmt.Errorf("invalid URL: %s", target)
+	}
+
+	type queued struct {
+		url    string
+		source string
+	}
+	var queue []queued
+	if targetURL.Path == "" || targetURL.Path == "/" {
+		sitemaps := c.robotsFor(ctx, targetURL).info.Sitemaps
+		for _, sitemap := range sitemaps {
+			queue = append(queue, queued{url: sitemap, source: models.SitemapSourceRobots})
+		}
+		if len(sitemaps) == 0 {
+			root := url.URL{Scheme: targetURL.Scheme, Host: targetURL.Host, Path: "/sitemap.xml"}
+			queue = append(queue, queued{url: root.String(), source: models.SitemapSourceDefault})
+		}
+	} else {
+		queue = append(queue, queued{url: targetURL.String(), source: models.SitemapSourceGiven})
+	}
+
+	addError := func(sitemap, loc, code, message string) {
+		report.Errors = append(report.Errors, models.SitemapError{Sitemap: sitemap, URL: loc, Code: code, Message: message})
+	}
+
+	seenSitemaps := map[string]bool{}
+	seenURLs := map[string]bool{}
+	for len(queue) > 0 && len(report.Sitemaps) < sitemapMaxFiles {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		current := queue[0]
+		queue = queue[1:]
+		if seenSitemaps[current.url] {
+			continue
+		}
+		seenSitemaps[current.url] = true
+
+		file, doc, code := c.readSitemap(ctx, current.url)
+		file.Source = current.source
+		report.Sitemaps = append(report.Sitemaps, file)
+		if doc == nil {
+			addError(file.URL, "", code, file.Error)
+			continue
+		}
+		sitemapURL, _ := url.Parse(file.URL)
+
+		if file.Index {
+			for _, entry := range doc.Sitemaps {
+				loc := strings.TrimSpace(entry.Loc)
+				if child, ok := sitemapLoc(sitemapURL, loc); ok {
+					queue = append(queue, queued{url: child.String(), source: models.SitemapSourceIndex})
+				} else {
+					addError(file.URL, loc, models.SitemapErrorInvalidURL, "The sitemap index lists a sitemap that is not an absolute http(s) URL")
+				}
+			}
+			continue
+		}
+
+		if len(doc.URLs) > sitemapMaxURLs {
+			addError(file.URL, "", models.SitemapErrorTooLarge, fmt.Sprintf("The sitemap lists %d URLs; only the first %d are read", len(doc.URLs), sitemapMaxURLs))
+			doc.URLs = doc.URLs[:sitemapMaxURLs]
+		}
+		for _, entry := range doc.URLs {
+			loc := strings.TrimSpace(entry.Loc)
+			entryURL, ok := sitemapLoc(sitemapURL, loc)
+			if !ok {
+				addError(file.URL, loc, models.SitemapErrorInvalidURL, "The URL is not an absolute http(s) URL")
+				continue
+			}
+			// Only the site's own pages are imported, wherever its sitemaps are hosted
+			if !strings.EqualFold(entryURL.Host, targetURL.Host) {
+				addError(file.URL, loc, models.SitemapErrorCrossHost, "The URL is on a different host than "+targetURL.Host+", so it is left out")
+				continue
+			}
+			lastMod := strings.TrimSpace(entry.LastMod)
+			if lastMod != "" {
+				if message := checkLastMod(lastMod); message != "" {
+					addError(file.URL, loc, models.SitemapErrorLastMod, message)
+				}
+			}
+
+			if seenURLs[entryURL.String()] {
+				continue
+			}
+			seenURLs[entryURL.String()] = true
+			if keep != nil && !keep(entryURL.String()) {
+				report.Filtered++
+				continue
+			}
+			report.URLs = append(report.URLs, models.SitemapURL{
+				Loc:        entryURL.String(),
+				LastMod:    lastMod,
+				ChangeFreq: strings.TrimSpace(entry.ChangeFreq),
+				Priority:   strings.TrimSpace(entry.Priority),
+				Sitemap:    file.URL,
+			})
+		}
+	}
+
+	report.Errors = append(report.Errors, c.checkSitemapURLs(ctx, &report)...)
+	return report, nil
+}
+
+// readSitemap fetches and parses one sitemap, returning the error code and
+// a nil document when it cannot be read
+func (c *CrawlerService) readSitemap(ctx context.Context, sitemapURL string) (models.SitemapFile, *sitemapDocument, string) {
+	file := models.SitemapFile{URL: sitemapURL}
+	fail := func(code, message string) (models.SitemapFile, *sitemapDocument, string) {
+		file.Error = message
+		return file, nil, code
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, sitemapURL, nil)
+	if err != nil {
+		return fail(models.SitemapErrorInvalidURL, err.Error())
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; WebsiteCrawler/1.0)")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fail(models.SitemapErrorFetch, err.Error())
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fail(models.SitemapErrorFetch, fmt.Sprintf("The sitemap returned status %d", resp.StatusCode))
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, sitemapSizeLimit+1))
+	if err != nil {
+		return fail(models.SitemapErrorFetch, err.Error())
+	}
+	// Gzipped sitemaps are served as files, so the transport leaves them packed
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		file.Gzipped = true
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return fail(models.SitemapErrorInvalidXML, "The sitemap is not valid gzip: "+err.Error())
+		}
+		if data, err = io.ReadAll(io.LimitReader(reader, sitemapSizeLimit+1)); err != nil {
+			return fail(models.SitemapErrorInvalidXML, "The sitemap is not valid gzip: "+err.Error())
+		}
+	}
+	if len(data) > sitemapSizeLimit {
+		return fail(models.SitemapErrorTooLarge, fmt.Sprintf("The sitemap is larger than %d MB uncompressed", sitemapSizeLimit>>20))
+	}
+
+	var doc sitemapDocument
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return fail(models.SitemapErrorInvalidXML, "The sitemap is not valid XML: "+err.Error())
+	}
+	switch doc.XMLName.Local {
+	case "urlset":
+		file.Entries = len(doc.URLs)
+	case "sitemapindex":
+		file.Index = true
+		file.Entries = len(doc.Sitemaps)
+	default:
+		return fail(models.SitemapErrorInvalidXML, fmt.Sprintf("The sitemap's root element is <%s>, not <urlset> or <sitemapindex>", doc.XMLName.Local))
+	}
+	return file, &doc, ""
+}
+
+// sitemapLoc parses a <loc>, which must be an absolute http(s) URL
+func sitemapLoc(sitemapURL *url.URL, loc string) (*url.URL, bool) {
+	if loc == "" || sitemapURL == nil {
+		return nil, false
+	}
+	locURL, err := url.Parse(loc)
+	if err != nil || locURL.Host == "" || (locURL.Scheme != "http" && locURL.Scheme != "https") {
+		return nil, false
+	}
+	locURL.Fragment = ""
+	return locURL, true
+}
+
+// checkLastMod returns what is wrong with a <lastmod>, or "" when it is valid
+func checkLastMod(lastMod string) string {
+	for _, layout := range lastModLayouts {
+		if t, err := time.Parse(layout, lastMod); err == nil {
+			// A day of slack covers time zones
+			if t.After(time.Now().Add(24 * time.Hour)) {
+				return "The lastmod " + lastMod + " is in the future"
+			}
+			return ""
+		}
+	}
+	return "The lastmod " + lastMod + " is not a W3C Datetime, such as 2024-01-31 or 2024-01-31T09:30:00+00:00"
+}
+
+// checkSitemapURLs requests the first URLs of a report, with the link
+// checker's concurrency limits, to find those that cannot be reached, do not
+// return 200, are disallowed by robots.txt or name another page as canonical
+func (c *CrawlerService) checkSitemapURLs(ctx context.Context, report *models.SitemapReport) []models.SitemapError {
+	limit := c.sitemapCheckLimit
+	if limit <= 0 {
+		limit = DefaultConfig().SitemapCheckLimit
+	}
+	toCheck := report.URLs[:min(limit, len(report.URLs))]
+	report.Checked = len(toCheck)
+	report.Unchecked = len(report.URLs) - len(toCheck)
+
+	lc := c.linkChecker
+	results := make([]*models.SitemapError, len(toCheck))
+	forEachIndex(len(toCheck), lc.concurrency, func(index int) {
+		entry := toCheck[index]
+		entryURL, err := url.Parse(entry.Loc)
+		if err != nil || ctx.Err() != nil {
+			return
+		}
+		problem := func(code, message string) {
+			results[index] = &models.SitemapError{Sitemap: entry.Sitemap, URL: entry.Loc, Code: code, Message: message}
+		}
+
+		robots := c.robotsFor(ctx, entryURL)
+		if robots.err != nil {
+			// Without robots.txt nothing is known about the URL, least of all that it is disallowed
+			problem(models.SitemapErrorUnreachable, "The URL's robots.txt could not be fetched: "+robots.err.Error())
+			return
+		}
+		if allowed, rule := robots.allows(entryURL); !allowed {
+			message := "robots.txt disallows the URL, so crawlers may not fetch it"
+			if rule != "" {
+				message += " (" + rule + ")"
+			}
+			problem(models.SitemapErrorDisallowed, message)
+			return
+		}
+
+		release, ok := lc.acquireHost(ctx, entryURL.Host)
+		if !ok {
+			return
+		}
+		code, message := c.checkSitemapURL(ctx, entryURL)
+		release()
+		if code != "" {
+			problem(code, message)
+		}
+	})
+
+	var problems []models.SitemapError
+	for _, result := range results {
+		if result != nil {
+			problems = append(problems, *result)
+		}
+	}
+	return problems
+}
+
+// checkSitemapURL fetches a sitemap URL without following redirects and
+// returns the problem found with it, if any
+func (c *CrawlerService) checkSitemapURL(ctx context.Context, entryURL *url.URL) (string, string) {
+	if c.linkChecker.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.linkChecker.timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, entryURL.String(), nil)
+	if err != nil {
+		return models.SitemapErrorInvalidURL, err.Error()
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; WebsiteCrawler/1.0)")
+
+	resp, err := c.pageClient.Do(req)
+	if err != nil {
+		return models.SitemapErrorUnreachable, "The URL could not be fetched: " + err.Error()
+	}
+	defer resp.Body.Close()
+	// The page client accepts any certificate, so a broken one is reported here
+	if resp.TLS != nil {
+		if err := c.tlsVerifier.verification(resp.TLS); err != nil {
+			return models.SitemapErrorUnreachable, "The URL's certificate is not valid: " + err.Error()
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		message := fmt.Sprintf("The URL returned status %d", resp.StatusCode)
+		if location, err := resp.Location(); err == nil {
+			message += " to " + location.String()
+		}
+		return models.SitemapErrorStatus, message
+	}
+
+	limit := c.maxBodySize
+	if limit <= 0 {
+		limit = DefaultConfig().MaxBodySize
+	}
+	body := newBodyReader(resp.Body, limit)
+	if documentType(resp.Header.Get("Content-Type"), body) != models.DocumentHTML {
+		return "", ""
+	}
+	doc, _, _, err := parseHTML(body, resp.Header.Get("Content-Type"))
+	if err != nil {
+		return "", ""
+	}
+
+	var canonical string
+	doc.Find("link[href]").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		if hasRelToken(s, "canonical") {
+			canonical = s.AttrOr("href", "")
+			return false
+		}
+		return true
+	})
+	if canonical == "" {
+		return "", ""
+	}
+	if canonicalURL, ok := resolveReference(entryURL, canonical); ok && normalizePageURL(canonicalURL) != normalizePageURL(entryURL) {
+		return models.SitemapErrorNonCanonical, "The page names " + canonicalURL.String() + " as its canonical URL; sitemaps should only list canonical URLs"
+	}
+	return "", ""
+}
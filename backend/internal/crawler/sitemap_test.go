@@ -0,0 +1,138 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"website-crawler/internal/models"
+)
+
+func TestCheckLastMod(t *testing.T) {
+	tests := []struct {
+		lastMod string
+		valid   bool
+	}{
+		{"2024", true},
+		{"2024-01", true},
+		{"2024-01-31", true},
+		{"2024-01-31T09:30Z", true},
+		{"2024-01-31T09:30+02:00", true},
+		{"2024-01-31T09:30:15Z", true},
+		{"2024-01-31T09:30:15.123-05:00", true},
+		{time.Now().Add(12 * time.Hour).UTC().Format(time.RFC3339), true},
+		{"2024-1-31", false},
+		{"2024-02-30", false},
+		{"31/01/2024", false},
+		{"2024-01-31 09:30:15", false},
+		{"2024-01-31T09:30:15", false},
+		{"Wed, 31 Jan 2024 09:30:15 GMT", false},
+		{time.Now().AddDate(0, 0, 3).Format("2006-01-02"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.lastMod, func(t *testing.T) {
+			message := checkLastMod(tt.lastMod)
+			if valid := message == ""; valid != tt.valid {
+				t.Errorf("checkLastMod(%q) = %q, want valid %v", tt.lastMod, message, tt.valid)
+			}
+		})
+	}
+}
+
+func TestSitemapLoc(t *testing.T) {
+	sitemapURL, _ := url.Parse("https://example.com/sitemap.xml")
+
+	tests := []struct {
+		loc  string
+		want string
+	}{
+		{"https://example.com/page", "https://example.com/page"},
+		{"http://example.com/page#section", "http://example.com/page"},
+		{"/page", ""},
+		{"page.html", ""},
+		{"ftp://example.com/file", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.loc, func(t *testing.T) {
+			got, ok := sitemapLoc(sitemapURL, tt.loc)
+			if tt.want == "" {
+				if ok {
+					t.Errorf("sitemapLoc(%q) = %s, want it rejected", tt.loc, got)
+				}
+				return
+			}
+			if !ok || got.String() != tt.want {
+				t.Errorf("sitemapLoc(%q) = %v, %v, want %s", tt.loc, got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadSitemaps(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			w.Write([]byte("User-agent: *\nDisallow: /private\nSitemap: " + server.URL + "/sitemap_index.xml\n"))
+		case "/sitemap_index.xml":
+			w.Write([]byte(`<sitemapindex><sitemap><loc>` + server.URL + `/pages.xml</loc></sitemap></sitemapindex>`))
+		case "/pages.xml":
+			w.Write([]byte(`<urlset>
+<url><loc>` + server.URL + `/ok</loc><lastmod>2024-01-31</lastmod></url>
+<url><loc>` + server.URL + `/ok</loc></url>
+<url><loc>` + server.URL + `/missing</loc><lastmod>yesterday</lastmod></url>
+<url><loc>` + server.URL + `/private</loc></url>
+<url><loc>https://other.example/page</loc></url>
+<url><loc>/relative</loc></url>
+</urlset>`))
+		case "/ok", "/private":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html><head><title>Page</title></head></html>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	report, err := NewCrawlerService().ReadSitemaps(context.Background(), server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Sitemaps) != 2 || report.Sitemaps[0].Source != models.SitemapSourceRobots || !report.Sitemaps[0].Index || report.Sitemaps[1].Source != models.SitemapSourceIndex {
+		t.Errorf("sitemaps %+v, want the index from robots.txt and its child", report.Sitemaps)
+	}
+
+	var locs []string
+	for _, entry := range report.URLs {
+		locs = append(locs, strings.TrimPrefix(entry.Loc, server.URL))
+	}
+	if want := []string{"/ok", "/missing", "/private"}; !reflect.DeepEqual(locs, want) {
+		t.Errorf("urls %v, want %v", locs, want)
+	}
+
+	codes := map[string]string{}
+	for _, problem := range report.Errors {
+		codes[strings.TrimPrefix(problem.URL, server.URL)] += problem.Code + " "
+	}
+	want := map[string]string{
+		"/missing":                   models.SitemapErrorLastMod + " " + models.SitemapErrorStatus + " ",
+		"/private":                   models.SitemapErrorDisallowed + " ",
+		"https://other.example/page": models.SitemapErrorCrossHost + " ",
+		"/relative":                  models.SitemapErrorInvalidURL + " ",
+	}
+	if !reflect.DeepEqual(codes, want) {
+		t.Errorf("errors %v, want %v", codes, want)
+	}
+	if report.Checked != 3 || report.Unchecked != 0 {
+		t.Errorf("checked %d, unchecked %d", report.Checked, report.Unchecked)
+	}
+}
@@ -0,0 +1,323 @@
+package crawler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"website-crawler/internal/models"
+
+	"github.com/PuerkitoBio/goquery"
+	nethtml "golang.org/x/net/html"
+)
+
+// requiredProperties lists the properties each schema.org type must carry.
+// Each inner slice is a set of alternatives of which at least one is needed.
+var requiredProperties = map[string][][]string{
+	"Product":        {{"name"}, {"offers", "review", "aggregateRating"}},
+	"Article":        {{"headline"}, {"author"}, {"datePublished"}},
+	"NewsArticle":    {{"headline"}, {"author"}, {"datePublished"}},
+	"BlogPosting":    {{"headline"}, {"author"}, {"datePublished"}},
+	"Organization":   {{"name"}, {"url"}},
+	"BreadcrumbList": {{"itemListElement"}},
+}
+
+// extractStructuredData collects the schema.org entities embedded in a page
+// as JSON-LD, Microdata and RDFa, and validates the common types
+func (c *CrawlerService) extractStructuredData(doc *goquery.Document) models.StructuredData {
+	data := models.StructuredData{
+		Entities: []models.StructuredEntity{},
+		Errors:   []models.StructuredDataError{},
+	}
+
+	doc.Find("script[type]").Each(func(i int, s *goquery.Selection) {
+		mediaType, _, _ := strings.Cut(s.AttrOr("type", ""), ";")
+		if !strings.EqualFold(strings.TrimSpace(mediaType), "application/ld+json") {
+			return
+		}
+		entities, err := parseJSONLD(s.Text())
+		if err != nil {
+			data.Errors = append(data.Errors, models.StructuredDataError{
+				Format:  models.FormatJSONLD,
+				Block:   i + 1,
+				Message: err.Error(),
+			})
+		}
+		data.Entities = append(data.Entities, entities...)
+	})
+
+	data.Entities = append(data.Entities, extractScopedItems(doc, models.FormatMicrodata, "itemscope", "itemprop")...)
+	data.Entities = append(data.Entities, extractScopedItems(doc, models.FormatRDFa, "typeof", "property")...)
+
+	for i := range data.Entities {
+		data.Entities[i].MissingProperties = missingProperties(data.Entities[i])
+		if len(data.Entities[i].MissingProperties) > 0 {
+			data.InvalidEntities++
+		}
+	}
+
+	return data
+}
+
+func (c *CrawlerService) structuredDataJSON(data models.StructuredData) string {
+	if jsonData, err := json.Marshal(data); err == nil {
+		return string(jsonData)
+	}
+	return "{}"
+}
+
+// parseJSONLD returns the top-level nodes of a JSON-LD block, including the
+// members of any @graph
+func parseJSONLD(block string) ([]models.StructuredEntity, error) {
+	var raw interface{}
+	if err := json.Unmarshal([]byte(block), &raw); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			line := strings.Count(block[:min(int(syntaxErr.Offset), len(block))], "\n") + 1
+			return nil, fmt.Errorf("invalid JSON on line %d of the block: %v", line, err)
+		}
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+
+	var entities []models.StructuredEntity
+	var visit func(value interface{})
+	visit = func(value interface{}) {
+		switch node := value.(type) {
+		case []interface{}:
+			for _, item := range node {
+				visit(item)
+			}
+		case map[string]interface{}:
+			if graph, ok := node["@graph"]; ok {
+				visit(graph)
+			}
+			types := jsonLDTypes(node["@type"])
+			if len(types) == 0 {
+				return
+			}
+			properties := make(map[string]interface{})
+			for key, prop := range node {
+				if !strings.HasPrefix(key, "@") {
+					properties[key] = prop
+				}
+			}
+			entities = append(entities, models.StructuredEntity{
+				Format:     models.FormatJSONLD,
+				Types:      types,
+				Properties: properties,
+			})
+		}
+	}
+	visit(raw)
+
+	return entities, nil
+}
+
+func jsonLDTypes(value interface{}) []string {
+	var types []string
+	switch t := value.(type) {
+	case string:
+		types = append(types, normalizeSchemaType(t))
+	case []interface{}:
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				types = append(types, normalizeSchemaType(s))
+			}
+		}
+	}
+	return types
+}
+
+// normalizeSchemaType strips vocabulary prefixes such as "https://schema.org/" or "schema:"
+func normalizeSchemaType(t string) string {
+	t = strings.TrimSpace(t)
+	if i := strings.LastIndexAny(t, "/:#"); i >= 0 {
+		t = t[i+1:]
+	}
+	return t
+}
+
+// extractScopedItems parses Microdata (itemscope/itemprop) or RDFa
+// (typeof/property) items. Only top-level items become entities; nested
+// items are kept as property values of their parent.
+func extractScopedItems(doc *goquery.Document, format, scopeAttr, propAttr string) []models.StructuredEntity {
+	var entities []models.StructuredEntity
+
+	doc.Find("[" + scopeAttr + "]").Each(func(i int, s *goquery.Selection) {
+		if _, nested := s.Attr(propAttr); nested {
+			return
+		}
+
+		node := s.Get(0)
+		typeValue := attrValue(node, scopeAttr)
+		if format == models.FormatMicrodata {
+			typeValue = attrValue(node, "itemtype")
+		}
+
+		var types []string
+		for _, t := range strings.Fields(typeValue) {
+			types = append(types, normalizeSchemaType(t))
+		}
+		if len(types) == 0 {
+			return
+		}
+
+		entities = append(entities, models.StructuredEntity{
+			Format:     format,
+			Types:      types,
+			Properties: scopedProperties(node, format, scopeAttr, propAttr),
+		})
+	})
+
+	return entities
+}
+
+// scopedProperties collects the properties that belong to the item rooted at node
+func scopedProperties(node *nethtml.Node, format, scopeAttr, propAttr string) map[string]interface{} {
+	properties := make(map[string]interface{})
+
+	var walk func(parent *nethtml.Node)
+	walk = func(parent *nethtml.Node) {
+		for child := parent.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != nethtml.ElementNode {
+				continue
+			}
+
+			_, isScope := nodeAttr(child, scopeAttr)
+			if names, ok := nodeAttr(child, propAttr); ok {
+				var value interface{}
+				if isScope {
+					nested := scopedProperties(child, format, scopeAttr, propAttr)
+					typeValue := attrValue(child, scopeAttr)
+					if format == models.FormatMicrodata {
+						typeValue = attrValue(child, "itemtype")
+					}
+					if t := strings.Fields(typeValue); len(t) > 0 {
+						nested["@type"] = normalizeSchemaType(t[0])
+					}
+					value = nested
+				} else {
+					value = scopedValue(child)
+				}
+
+				for _, name := range strings.Fields(names) {
+					name = normalizeSchemaType(name)
+					addProperty(properties, name, value)
+				}
+			}
+
+			// Properties below a nested scope belong to that scope
+			if !isScope {
+				walk(child)
+			}
+		}
+	}
+	walk(node)
+
+	return properties
+}
+
+// scopedValue returns the value of a property element following the
+// Microdata rules, with RDFa's content attribute taking precedence
+func scopedValue(node *nethtml.Node) string {
+	if content, ok := nodeAttr(node, "content"); ok {
+		return strings.TrimSpace(content)
+	}
+
+	var attr string
+	switch node.Data {
+	case "a", "area", "link":
+		attr = "href"
+	case "img", "audio", "video", "source", "iframe", "embed", "track":
+		attr = "src"
+	case "object":
+		attr = "data"
+	case "time":
+		attr = "datetime"
+	case "data", "meter":
+		attr = "value"
+	}
+	if attr != "" {
+		if value, ok := nodeAttr(node, attr); ok {
+			return strings.TrimSpace(value)
+		}
+	}
+	if value, ok := nodeAttr(node, "resource"); ok {
+		return strings.TrimSpace(value)
+	}
+
+	return strings.Join(strings.Fields(goquery.NewDocumentFromNode(node).Text()), " ")
+}
+
+func addProperty(properties map[string]interface{}, name string, value interface{}) {
+	existing, ok := properties[name]
+	if !ok {
+		properties[name] = value
+		return
+	}
+	if values, isList := existing.([]interface{}); isList {
+		properties[name] = append(values, value)
+		return
+	}
+	properties[name] = []interface{}{existing, value}
+}
+
+// missingProperties returns the required properties an entity lacks,
+// including position and name on every breadcrumb item
+func missingProperties(entity models.StructuredEntity) []string {
+	var missing []string
+
+	for _, t := range entity.Types {
+		for _, alternatives := range requiredProperties[t] {
+			found := false
+			for _, name := range alternatives {
+				if hasValue(entity.Properties[name]) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				missing = append(missing, strings.Join(alternatives, " or "))
+			}
+		}
+
+		if t == "BreadcrumbList" {
+			items, _ := entity.Properties["itemListElement"].([]interface{})
+			if single, ok := entity.Properties["itemListElement"].(map[string]interface{}); ok {
+				items = []interface{}{single}
+			}
+			for i, item := range items {
+				listItem, _ := item.(map[string]interface{})
+				for _, name := range []string{"position", "name"} {
+					if !hasValue(listItem[name]) && !hasNestedName(listItem, name) {
+						missing = append(missing, fmt.Sprintf("itemListElement[%d].%s", i, name))
+					}
+				}
+			}
+		}
+	}
+
+	return missing
+}
+
+// hasNestedName allows a breadcrumb's name to come from its item, as schema.org permits
+func hasNestedName(listItem map[string]interface{}, name string) bool {
+	if name != "name" {
+		return false
+	}
+	item, ok := listItem["item"].(map[string]interface{})
+	return ok && hasValue(item["name"])
+}
+
+func hasValue(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case string:
+		return strings.TrimSpace(v) != ""
+	case []interface{}:
+		return len(v) > 0
+	}
+	return true
+}
@@ -0,0 +1,98 @@
+package crawler
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"website-crawler/internal/models"
+)
+
+func TestExtractStructuredData(t *testing.T) {
+	doc := newTestDocument(t, `<html><head>
+<script type="application/ld+json">
+{"@context": "https://schema.org", "@graph": [
+  {"@type": "Organization", "name": "Example", "url": "https://example.com"},
+  {"@type": "BreadcrumbList", "itemListElement": [
+    {"@type": "ListItem", "position": 1, "item": {"@id": "/", "name": "Home"}},
+    {"@type": "ListItem", "position": 2}
+  ]}
+]}
+</script>
+<script type="application/ld+json; charset=utf-8">
+{"@type": "Article",
+ "headline": "Broken"
+</script>
+<script type="text/javascript">var ignored = true;</script>
+</head><body>
+<div itemscope itemtype="https://schema.org/Product">
+  <span itemprop="name">Kettle</span>
+  <div itemprop="offers" itemscope itemtype="https://schema.org/Offer">
+    <meta itemprop="price" content="19.99"><span itemprop="name">Not the product name</span>
+  </div>
+  <a itemprop="url" href="https://example.com/kettle">link</a>
+</div>
+<div vocab="https://schema.org/" typeof="Article">
+  <h1 property="headline">Hello</h1>
+  <time property="datePublished" datetime="2024-01-01">New Year</time>
+</div>
+</body></html>`)
+
+	data := NewCrawlerService().extractStructuredData(doc)
+
+	if len(data.Errors) != 1 || data.Errors[0].Format != models.FormatJSONLD || data.Errors[0].Block != 2 ||
+		!strings.Contains(data.Errors[0].Message, "invalid JSON") {
+		t.Errorf("errors %+v, want one invalid JSON error for the second block", data.Errors)
+	}
+
+	byType := map[string]models.StructuredEntity{}
+	for _, entity := range data.Entities {
+		byType[entity.Types[0]] = entity
+	}
+	if len(data.Entities) != 4 {
+		t.Fatalf("got %d entities, want 4: %+v", len(data.Entities), data.Entities)
+	}
+
+	if org := byType["Organization"]; org.Format != models.FormatJSONLD || len(org.MissingProperties) != 0 {
+		t.Errorf("organization %+v", org)
+	}
+	breadcrumbs := byType["BreadcrumbList"]
+	if want := []string{"itemListElement[1].name"}; !reflect.DeepEqual(breadcrumbs.MissingProperties, want) {
+		t.Errorf("breadcrumbs miss %v, want %v", breadcrumbs.MissingProperties, want)
+	}
+
+	product := byType["Product"]
+	if product.Format != models.FormatMicrodata || product.Properties["name"] != "Kettle" || product.Properties["url"] != "https://example.com/kettle" {
+		t.Errorf("product %+v", product)
+	}
+	offer, _ := product.Properties["offers"].(map[string]interface{})
+	if offer["@type"] != "Offer" || offer["price"] != "19.99" || len(product.MissingProperties) != 0 {
+		t.Errorf("offer %+v, missing %v", offer, product.MissingProperties)
+	}
+
+	article := byType["Article"]
+	if article.Format != models.FormatRDFa || article.Properties["datePublished"] != "2024-01-01" {
+		t.Errorf("article %+v", article)
+	}
+	if want := []string{"author"}; !reflect.DeepEqual(article.MissingProperties, want) {
+		t.Errorf("article misses %v, want %v", article.MissingProperties, want)
+	}
+
+	if data.InvalidEntities != 2 {
+		t.Errorf("%d invalid entities, want 2", data.InvalidEntities)
+	}
+}
+
+func TestNormalizeSchemaType(t *testing.T) {
+	for value, want := range map[string]string{
+		"Product":                    "Product",
+		"https://schema.org/Product": "Product",
+		"http://schema.org/Product ": "Product",
+		"schema:Product":             "Product",
+		"http://schema.org#ListItem": "ListItem",
+	} {
+		if got := normalizeSchemaType(value); got != want {
+			t.Errorf("normalizeSchemaType(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
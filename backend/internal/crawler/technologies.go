@@ -0,0 +1,337 @@
+package crawler
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"website-crawler/internal/models"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// builtinTechnologies is the default rule file. Deployments can replace it
+// without a rebuild through Config.TechnologyRulesFile.
+//
+//go:embed technologies.json
+var builtinTechnologies []byte
+
+// TechnologyRule describes how to recognise a technology. Patterns are
+// case-insensitive regular expressions; an empty pattern only requires the
+// header, cookie or meta tag to be present. A pattern may end in
+// "\;version:\1" to read the version from a capture group. Cookie names may
+// end in "*" to match a prefix.
+type TechnologyRule struct {
+	Name       string            `json:"name"`
+	Categories []string          `json:"categories"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Cookies    map[string]string `json:"cookies,omitempty"`
+	Meta       map[string]string `json:"meta,omitempty"`
+	Scripts    []string          `json:"scripts,omitempty"`
+	HTML       []string          `json:"html,omitempty"`
+	Implies    []string          `json:"implies,omitempty"`
+}
+
+// techPattern is a compiled rule pattern with its version template
+type techPattern struct {
+	source  string
+	regex   *regexp.Regexp
+	version string
+}
+
+// technology is a compiled TechnologyRule
+type technology struct {
+	rule    TechnologyRule
+	headers map[string]techPattern
+	cookies map[string]techPattern
+	meta    map[string]techPattern
+	scripts []techPattern
+	html    []techPattern
+}
+
+// ParseTechnologyRules reads a rule file and checks that every rule compiles
+func ParseTechnologyRules(data []byte) ([]TechnologyRule, error) {
+	var rules []TechnologyRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("invalid technology rules: %w", err)
+	}
+	for _, rule := range rules {
+		if err := ValidateTechnologyRule(rule); err != nil {
+			return nil, err
+		}
+	}
+	return rules, nil
+}
+
+// ValidateTechnologyRule reports whether a rule has a name and valid patterns
+func ValidateTechnologyRule(rule TechnologyRule) error {
+	_, err := compileTechnology(rule)
+	return err
+}
+
+// loadTechnologies compiles the rule file at path, or the built-in rules when
+// path is empty or cannot be used
+func loadTechnologies(path string) []*technology {
+	data := builtinTechnologies
+	if path != "" {
+		if fileData, err := os.ReadFile(path); err == nil {
+			data = fileData
+		} else {
+			log.Printf("Failed to read technology rules from %s, using the built-in rules: %v", path, err)
+		}
+	}
+
+	rules, err := ParseTechnologyRules(data)
+	if err != nil && path != "" {
+		log.Printf("Failed to load technology rules from %s, using the built-in rules: %v", path, err)
+		rules, err = ParseTechnologyRules(builtinTechnologies)
+	}
+	if err != nil {
+		panic("crawler: invalid built-in technology rules: " + err.Error())
+	}
+
+	technologies := make([]*technology, 0, len(rules))
+	for _, rule := range rules {
+		tech, _ := compileTechnology(rule)
+		technologies = append(technologies, tech)
+	}
+	return technologies
+}
+
+func compileTechnology(rule TechnologyRule) (*technology, error) {
+	if strings.TrimSpace(rule.Name) == "" {
+		return nil, errors.New("technology rule has no name")
+	}
+
+	tech := &technology{
+		rule:    rule,
+		headers: map[string]techPattern{},
+		cookies: map[string]techPattern{},
+		meta:    map[string]techPattern{},
+	}
+	compileMap := func(patterns map[string]string, into map[string]techPattern, lowerKeys bool) error {
+		for key, raw := range patterns {
+			pattern, err := compileTechPattern(raw)
+			if err != nil {
+				return fmt.Errorf("technology %s: %w", rule.Name, err)
+			}
+			if lowerKeys {
+				key = strings.ToLower(key)
+			}
+			into[key] = pattern
+		}
+		return nil
+	}
+	compileList := func(patterns []string) ([]techPattern, error) {
+		var compiled []techPattern
+		for _, raw := range patterns {
+			pattern, err := compileTechPattern(raw)
+			if err != nil {
+				return nil, fmt.Errorf("technology %s: %w", rule.Name, err)
+			}
+			compiled = append(compiled, pattern)
+		}
+		return compiled, nil
+	}
+
+	var err error
+	if err = compileMap(rule.Headers, tech.headers, false); err != nil {
+		return nil, err
+	}
+	if err = compileMap(rule.Cookies, tech.cookies, false); err != nil {
+		return nil, err
+	}
+	if err = compileMap(rule.Meta, tech.meta, true); err != nil {
+		return nil, err
+	}
+	if tech.scripts, err = compileList(rule.Scripts); err != nil {
+		return nil, err
+	}
+	if tech.html, err = compileList(rule.HTML); err != nil {
+		return nil, err
+	}
+	return tech, nil
+}
+
+func compileTechPattern(raw string) (techPattern, error) {
+	expression, tags, _ := strings.Cut(raw, `\;`)
+	pattern := techPattern{source: expression}
+	for _, tag := range strings.Split(tags, `\;`) {
+		if version, ok := strings.CutPrefix(tag, "version:"); ok {
+			pattern.version = version
+		}
+	}
+
+	regex, err := regexp.Compile("(?i)" + expression)
+	if err != nil {
+		return techPattern{}, fmt.Errorf("invalid pattern %q: %w", expression, err)
+	}
+	pattern.regex = regex
+	return pattern, nil
+}
+
+// match reports whether value matches, along with the version it carries
+func (p techPattern) match(value string) (bool, string) {
+	groups := p.regex.FindStringSubmatch(value)
+	if groups == nil {
+		return false, ""
+	}
+	if p.version == "" {
+		return true, ""
+	}
+
+	version := p.version
+	for i := len(groups) - 1; i >= 1; i-- {
+		version = strings.ReplaceAll(version, `\`+strconv.Itoa(i), groups[i])
+	}
+	return true, strings.TrimSpace(version)
+}
+
+// technologyMatch accumulates the evidence for one detected technology
+type technologyMatch struct {
+	rule     TechnologyRule
+	version  string
+	evidence []string
+}
+
+// compileTechnologies compiles per-URL rules, skipping any that do not
+// compile, so a crawl compiles them once however many pages it analyzes
+func compileTechnologies(rules []TechnologyRule) []*technology {
+	var technologies []*technology
+	for _, rule := range rules {
+		if tech, err := compileTechnology(rule); err == nil {
+			technologies = append(technologies, tech)
+		}
+	}
+	return technologies
+}
+
+// detectTechnologies fingerprints the page against the loaded rules and the
+// given extra compiled rules, then adds the technologies the matches imply
+func (c *CrawlerService) detectTechnologies(header http.Header, cookies []*http.Cookie, doc *goquery.Document, refs []resourceRef, source string, extra []*technology) []models.DetectedTechnology {
+	technologies := append(c.technologies[:len(c.technologies):len(c.technologies)], extra...)
+
+	metaTags := map[string][]string{}
+	doc.Find("meta[name][content]").Each(func(i int, s *goquery.Selection) {
+		name := strings.ToLower(strings.TrimSpace(s.AttrOr("name", "")))
+		metaTags[name] = append(metaTags[name], s.AttrOr("content", ""))
+	})
+	var scripts []string
+	for _, ref := range refs {
+		if ref.Type == models.ResourceScript {
+			scripts = append(scripts, ref.URL.String())
+		}
+	}
+
+	matches := map[string]*technologyMatch{}
+	var order []string
+	found := func(rule TechnologyRule, version, evidence string) {
+		m, ok := matches[rule.Name]
+		if !ok {
+			m = &technologyMatch{rule: rule}
+			matches[rule.Name] = m
+			order = append(order, rule.Name)
+		}
+		// The most specific version wins
+		if len(version) > len(m.version) {
+			m.version = version
+		}
+		m.evidence = append(m.evidence, evidence)
+	}
+
+	for _, tech := range technologies {
+		for name, pattern := range tech.headers {
+			for _, value := range header.Values(name) {
+				if ok, version := pattern.match(value); ok {
+					found(tech.rule, version, fmt.Sprintf("header %s: %s", name, value))
+					break
+				}
+			}
+		}
+		for name, pattern := range tech.cookies {
+			for _, cookie := range cookies {
+				if !cookieNameMatches(name, cookie.Name) {
+					continue
+				}
+				if ok, version := pattern.match(cookie.Value); ok {
+					found(tech.rule, version, "cookie "+cookie.Name)
+					break
+				}
+			}
+		}
+		for name, pattern := range tech.meta {
+			for _, content := range metaTags[name] {
+				if ok, version := pattern.match(content); ok {
+					found(tech.rule, version, fmt.Sprintf("meta %s: %s", name, content))
+					break
+				}
+			}
+		}
+		for _, pattern := range tech.scripts {
+			for _, script := range scripts {
+				if ok, version := pattern.match(script); ok {
+					found(tech.rule, version, "script "+script)
+					break
+				}
+			}
+		}
+		for _, pattern := range tech.html {
+			if ok, version := pattern.match(source); ok {
+				found(tech.rule, version, "html matches "+pattern.source)
+			}
+		}
+	}
+
+	// Implied technologies, such as PHP under WordPress, are added transitively
+	rules := map[string]TechnologyRule{}
+	for _, tech := range technologies {
+		rules[tech.rule.Name] = tech.rule
+	}
+	for i := 0; i < len(order); i++ {
+		m := matches[order[i]]
+		for _, implied := range m.rule.Implies {
+			if _, ok := matches[implied]; ok {
+				continue
+			}
+			rule, ok := rules[implied]
+			if !ok {
+				rule = TechnologyRule{Name: implied}
+			}
+			found(rule, "", "implied by "+m.rule.Name)
+		}
+	}
+
+	sort.Strings(order)
+	detected := make([]models.DetectedTechnology, 0, len(order))
+	for _, name := range order {
+		m := matches[name]
+		categories := m.rule.Categories
+		if categories == nil {
+			categories = []string{}
+		}
+		categoriesJSON, _ := json.Marshal(categories)
+		evidenceJSON, _ := json.Marshal(m.evidence)
+		detected = append(detected, models.DetectedTechnology{
+			Name:       name,
+			Version:    m.version,
+			Categories: string(categoriesJSON),
+			Evidence:   string(evidenceJSON),
+		})
+	}
+	return detected
+}
+
+func cookieNameMatches(pattern, name string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(name, prefix)
+	}
+	return pattern == name
+}
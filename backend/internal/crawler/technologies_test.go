@@ -0,0 +1,175 @@
+package crawler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newTechnologyService returns a crawler that only knows the given rules
+func newTechnologyService(t *testing.T, rules []TechnologyRule) *CrawlerService {
+	t.Helper()
+	c := NewCrawlerService()
+	c.technologies = nil
+	for _, rule := range rules {
+		tech, err := compileTechnology(rule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.technologies = append(c.technologies, tech)
+	}
+	return c
+}
+
+func TestDetectTechnologies(t *testing.T) {
+	c := newTechnologyService(t, []TechnologyRule{
+		{Name: "Nginx", Categories: []string{"Web servers"}, Headers: map[string]string{"Server": `nginx(?:/([\d.]+))?\;version:\1`}},
+		{Name: "PHP", Categories: []string{"Programming languages"}, Headers: map[string]string{"X-Powered-By": `php/?([\d.]+)?\;version:\1`}},
+		{Name: "WordPress", Categories: []string{"CMS"}, Meta: map[string]string{"Generator": `WordPress ?([\d.]+)?\;version:\1`}, HTML: []string{`/wp-content/`}, Implies: []string{"PHP", "MySQL"}},
+		{Name: "WooCommerce", Categories: []string{"Ecommerce"}, HTML: []string{`woocommerce`}, Implies: []string{"WordPress"}},
+		{Name: "jQuery", Categories: []string{"JavaScript libraries"}, Scripts: []string{`jquery[.-]([\d.]+)(?:\.min)?\.js\;version:\1`}},
+		{Name: "PrestaShop", Cookies: map[string]string{"PrestaShop-*": ""}},
+		{Name: "Laravel", Cookies: map[string]string{"laravel_session": ""}, Implies: []string{"PHP"}},
+		{Name: "React", Scripts: []string{`react(?:\.production)?\.min\.js`}},
+	})
+
+	markup := `<html><head><meta name="generator" content="WordPress 6.4.2">
+<script src="/wp-includes/js/jquery/jquery-3.7.1.min.js"></script></head>
+<body class="woocommerce"><img src="/wp-content/uploads/logo.png"></body></html>`
+	pageURL, _ := url.Parse("https://shop.example.com/")
+	doc := newTestDocument(t, markup)
+	refs := collectReferences(doc, pageURL, newSourceLocator(markup))
+
+	header := http.Header{}
+	header.Set("Server", "nginx/1.25.3")
+	header.Set("X-Powered-By", "PHP/8.2.1")
+	cookies := []*http.Cookie{{Name: "PrestaShop-a1b2c3", Value: "x"}, {Name: "session", Value: "y"}}
+
+	detected := c.detectTechnologies(header, cookies, doc, refs, markup, nil)
+
+	versions := map[string]string{}
+	var names []string
+	for _, tech := range detected {
+		names = append(names, tech.Name)
+		versions[tech.Name] = tech.Version
+	}
+	want := []string{"MySQL", "Nginx", "PHP", "PrestaShop", "WooCommerce", "WordPress", "jQuery"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("detected %v, want %v", names, want)
+	}
+	for name, version := range map[string]string{"Nginx": "1.25.3", "PHP": "8.2.1", "WordPress": "6.4.2", "jQuery": "3.7.1", "MySQL": ""} {
+		if versions[name] != version {
+			t.Errorf("%s version %q, want %q", name, versions[name], version)
+		}
+	}
+
+	for _, tech := range detected {
+		var categories, evidence []string
+		if err := json.Unmarshal([]byte(tech.Categories), &categories); err != nil {
+			t.Errorf("%s categories %q: %v", tech.Name, tech.Categories, err)
+		}
+		if err := json.Unmarshal([]byte(tech.Evidence), &evidence); err != nil || len(evidence) == 0 {
+			t.Errorf("%s evidence %q: %v", tech.Name, tech.Evidence, err)
+		}
+		if tech.Name == "MySQL" && (len(categories) != 0 || evidence[0] != "implied by WordPress") {
+			t.Errorf("MySQL should only be implied: categories %v, evidence %v", categories, evidence)
+		}
+	}
+}
+
+func TestDetectTechnologiesImpliesTransitively(t *testing.T) {
+	c := newTechnologyService(t, []TechnologyRule{
+		{Name: "PHP"},
+		{Name: "WordPress", Implies: []string{"PHP"}},
+		{Name: "WooCommerce", HTML: []string{`woocommerce`}, Implies: []string{"WordPress"}},
+	})
+
+	markup := `<html><body class="woocommerce"></body></html>`
+	detected := c.detectTechnologies(http.Header{}, nil, newTestDocument(t, markup), nil, markup, nil)
+
+	evidence := map[string]string{}
+	for _, tech := range detected {
+		evidence[tech.Name] = tech.Evidence
+	}
+	if len(detected) != 3 || !strings.Contains(evidence["WordPress"], "implied by WooCommerce") || !strings.Contains(evidence["PHP"], "implied by WordPress") {
+		t.Errorf("want WooCommerce, then WordPress and PHP through implies: %+v", detected)
+	}
+}
+
+func TestDetectTechnologiesBuiltinCookies(t *testing.T) {
+	markup := `<html><body></body></html>`
+	cookies := []*http.Cookie{{Name: "PrestaShop-0123abcd", Value: "x"}}
+
+	detected := NewCrawlerService().detectTechnologies(http.Header{}, cookies, newTestDocument(t, markup), nil, markup, nil)
+	for _, tech := range detected {
+		if tech.Name == "PrestaShop" {
+			return
+		}
+	}
+	t.Errorf("a per-shop PrestaShop cookie should be detected with the built-in rules: %+v", detected)
+}
+
+func TestDetectTechnologiesUserRules(t *testing.T) {
+	c := newTechnologyService(t, []TechnologyRule{{Name: "Nginx", Headers: map[string]string{"Server": "nginx"}}})
+
+	markup := `<html><body><div id="acme-widget" data-version="2.1"></div></body></html>`
+	header := http.Header{"Server": {"nginx"}}
+	extra := []TechnologyRule{
+		{Name: "Acme Widget", HTML: []string{`id="acme-widget" data-version="([\d.]+)"\;version:\1`}},
+		{Name: "Broken", HTML: []string{`(unclosed`}},
+	}
+
+	detected := c.detectTechnologies(header, nil, newTestDocument(t, markup), nil, markup, compileTechnologies(extra))
+	if len(detected) != 2 || detected[0].Name != "Acme Widget" || detected[0].Version != "2.1" || detected[1].Name != "Nginx" {
+		t.Errorf("want the user rule alongside the built-in ones and the broken rule skipped: %+v", detected)
+	}
+	if len(c.technologies) != 1 {
+		t.Errorf("user rules leaked into the shared rule set: %d rules", len(c.technologies))
+	}
+}
+
+func TestParseTechnologyRules(t *testing.T) {
+	rules, err := ParseTechnologyRules(builtinTechnologies)
+	if err != nil || len(rules) == 0 {
+		t.Fatalf("built-in rules: %d rules, %v", len(rules), err)
+	}
+
+	tests := []struct {
+		name string
+		data string
+		ok   bool
+	}{
+		{"valid", `[{"name": "Acme", "html": ["acme"]}]`, true},
+		{"not json", `{`, false},
+		{"missing name", `[{"html": ["acme"]}]`, false},
+		{"bad pattern", `[{"name": "Acme", "headers": {"Server": "acme("}}]`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseTechnologyRules([]byte(tt.data)); (err == nil) != tt.ok {
+				t.Errorf("ParseTechnologyRules(%s) error = %v, want ok %v", tt.data, err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestCookieNameMatches(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"laravel_session", "laravel_session", true},
+		{"laravel_session", "laravel_session_2", false},
+		{"PrestaShop-*", "PrestaShop-0123abcd", true},
+		{"PrestaShop-*", "prestashop-0123abcd", false},
+	}
+	for _, tt := range tests {
+		if got := cookieNameMatches(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("cookieNameMatches(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
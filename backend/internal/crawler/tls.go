@@ -0,0 +1,184 @@
+package crawler
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"website-crawler/internal/models"
+)
+
+// tlsVerificationTTL is how long a chain's verification outcome is kept,
+// comfortably longer than an idle connection is reused
+const tlsVerificationTTL = 10 * time.Minute
+
+// tlsVerifier checks peer chains by hand for clients that accept any chain,
+// so the chain of a site with a broken certificate can still be inspected.
+// It remembers each outcome for inspectTLS to report.
+type tlsVerifier struct {
+	// roots are the trusted roots, the system pool when nil
+	roots   *x509.CertPool
+	mutex   sync.Mutex
+	results map[[sha256.Size]byte]tlsVerification
+}
+
+type tlsVerification struct {
+	err     error
+	expires time.Time
+}
+
+func newTLSVerifier() *tlsVerifier {
+	return &tlsVerifier{results: map[[sha256.Size]byte]tlsVerification{}}
+}
+
+// verifyConnection is a tls.Config.VerifyConnection that records whether the
+// chain verifies instead of failing the handshake
+func (v *tlsVerifier) verifyConnection(state tls.ConnectionState) error {
+	err := v.verifyChain(state)
+
+	now := time.Now()
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	for key, result := range v.results {
+		if now.After(result.expires) {
+			delete(v.results, key)
+		}
+	}
+	v.results[chainKey(state)] = tlsVerification{err: err, expires: now.Add(tlsVerificationTTL)}
+	return nil
+}
+
+// verification returns the recorded outcome of verifying a connection's
+// chain, verifying it again if the record is gone
+func (v *tlsVerifier) verification(state *tls.ConnectionState) error {
+	v.mutex.Lock()
+	result, ok := v.results[chainKey(*state)]
+	v.mutex.Unlock()
+	if ok {
+		return result.err
+	}
+	return v.verifyChain(*state)
+}
+
+// verifyChain verifies the peer chain against the trusted roots and the
+// server name, the way the TLS client would have
+func (v *tlsVerifier) verifyChain(state tls.ConnectionState) error {
+	if len(state.PeerCertificates) == 0 {
+		return errors.New("the server sent no certificate")
+	}
+	opts := x509.VerifyOptions{
+		Roots:         v.roots,
+		DNSName:       state.ServerName,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range state.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	_, err := state.PeerCertificates[0].Verify(opts)
+	return err
+}
+
+// chainKey identifies a peer chain presented for a server name
+func chainKey(state tls.ConnectionState) [sha256.Size]byte {
+	hash := sha256.New()
+	hash.Write([]byte(state.ServerName))
+	for _, cert := range state.PeerCertificates {
+		hash.Write(cert.Raw)
+	}
+	var key [sha256.Size]byte
+	hash.Sum(key[:0])
+	return key
+}
+
+// inspectTLS describes the negotiated connection and peer certificate chain
+// of an https response, including whether the chain verified. It returns nil
+// for plain http responses.
+func (c *CrawlerService) inspectTLS(state *tls.ConnectionState, host string) *models.TLSInfo {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return nil
+	}
+
+	leaf := state.PeerCertificates[0]
+	info := &models.TLSInfo{
+		Version:       tls.VersionName(state.Version),
+		CipherSuite:   tls.CipherSuiteName(state.CipherSuite),
+		HostnameMatch: leaf.VerifyHostname(host) == nil,
+		Chain:         make([]models.CertificateInfo, 0, len(state.PeerCertificates)),
+	}
+	if err := c.tlsVerifier.verification(state); err != nil {
+		info.VerifyError = err.Error()
+	} else {
+		info.Verified = true
+	}
+
+	for _, cert := range state.PeerCertificates {
+		keyType, keySize := publicKeyInfo(cert)
+		info.Chain = append(info.Chain, models.CertificateInfo{
+			Subject:      cert.Subject.String(),
+			Issuer:       cert.Issuer.String(),
+			SANs:         subjectAltNames(cert),
+			SerialNumber: strings.ToUpper(cert.SerialNumber.Text(16)),
+			NotBefore:    cert.NotBefore,
+			NotAfter:     cert.NotAfter,
+			KeyType:      keyType,
+			KeySize:      keySize,
+			IsCA:         cert.IsCA,
+		})
+	}
+
+	return info
+}
+
+// chainExpiry returns when the first certificate of a chain expires, which
+// ends the chain's validity whichever certificate it is
+func chainExpiry(info *models.TLSInfo) time.Time {
+	expiresAt := info.Chain[0].NotAfter
+	for _, cert := range info.Chain[1:] {
+		if cert.NotAfter.Before(expiresAt) {
+			expiresAt = cert.NotAfter
+		}
+	}
+	return expiresAt
+}
+
+func (c *CrawlerService) tlsInfoJSON(info *models.TLSInfo) string {
+	if info == nil {
+		return "null"
+	}
+	if jsonData, err := json.Marshal(info); err == nil {
+		return string(jsonData)
+	}
+	return "null"
+}
+
+func publicKeyInfo(cert *x509.Certificate) (string, int) {
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return "RSA", key.N.BitLen()
+	case *ecdsa.PublicKey:
+		return "ECDSA", key.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		return "Ed25519", 256
+	}
+	return cert.PublicKeyAlgorithm.String(), 0
+}
+
+func subjectAltNames(cert *x509.Certificate) []string {
+	names := append([]string{}, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		names = append(names, ip.String())
+	}
+	names = append(names, cert.EmailAddresses...)
+	for _, uri := range cert.URIs {
+		names = append(names, uri.String())
+	}
+	return names
+}
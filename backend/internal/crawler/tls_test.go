@@ -0,0 +1,109 @@
+package crawler
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"website-crawler/internal/models"
+)
+
+// testTLSState returns the connection state of a request to an httptest TLS server
+func testTLSState(t *testing.T) *tls.ConnectionState {
+	t.Helper()
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(server.Close)
+
+	resp, err := server.Client().Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.TLS
+}
+
+func TestInspectTLS(t *testing.T) {
+	state := testTLSState(t)
+	c := NewCrawlerService()
+
+	if info := c.inspectTLS(nil, "127.0.0.1"); info != nil {
+		t.Errorf("plain http produced TLS info: %+v", info)
+	}
+
+	info := c.inspectTLS(state, "127.0.0.1")
+	if info == nil {
+		t.Fatal("no TLS info for an https response")
+	}
+	if info.Verified || info.VerifyError == "" {
+		t.Errorf("a self-signed chain should not verify against the system roots: verified %v, error %q", info.Verified, info.VerifyError)
+	}
+	if info.Version != "TLS 1.3" || info.CipherSuite == "" || !info.HostnameMatch {
+		t.Errorf("version %q, cipher %q, hostname match %v", info.Version, info.CipherSuite, info.HostnameMatch)
+	}
+	if len(info.Chain) != len(state.PeerCertificates) {
+		t.Fatalf("chain has %d certificates, want %d", len(info.Chain), len(state.PeerCertificates))
+	}
+
+	leaf := info.Chain[0]
+	if leaf.KeyType != "RSA" || leaf.KeySize != 2048 || leaf.SerialNumber == "" || !leaf.NotAfter.Equal(state.PeerCertificates[0].NotAfter) {
+		t.Errorf("unexpected leaf details: %+v", leaf)
+	}
+	sans := map[string]bool{}
+	for _, name := range leaf.SANs {
+		sans[name] = true
+	}
+	if !sans["example.com"] || !sans["127.0.0.1"] {
+		t.Errorf("SANs %v should list example.com and 127.0.0.1", leaf.SANs)
+	}
+
+	if other := c.inspectTLS(state, "other.test"); other.HostnameMatch {
+		t.Error("certificate was reported to match a host it does not cover")
+	}
+}
+
+func TestInspectTLSTrustedRoot(t *testing.T) {
+	state := testTLSState(t)
+	c := NewCrawlerService()
+	c.tlsVerifier.roots = x509.NewCertPool()
+	c.tlsVerifier.roots.AddCert(state.PeerCertificates[len(state.PeerCertificates)-1])
+
+	if info := c.inspectTLS(state, "127.0.0.1"); !info.Verified || info.VerifyError != "" {
+		t.Errorf("the chain should verify against its own root: verified %v, error %q", info.Verified, info.VerifyError)
+	}
+}
+
+func TestCrawlWebsiteUntrustedCertificate(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><head><title>Untrusted</title></head></html>"))
+	}))
+	defer server.Close()
+
+	analysis, err := NewCrawlerService().CrawlWebsite(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("a page with an untrusted certificate should still be analyzed: %v", err)
+	}
+	var info models.TLSInfo
+	if err := json.Unmarshal([]byte(analysis.TLSInfo), &info); err != nil {
+		t.Fatalf("tls info %q: %v", analysis.TLSInfo, err)
+	}
+	if analysis.Title != "Untrusted" || info.Verified || info.VerifyError == "" || len(info.Chain) == 0 {
+		t.Errorf("want the page analyzed with its chain reported unverified: title %q, tls %+v", analysis.Title, info)
+	}
+}
+
+func TestChainExpiry(t *testing.T) {
+	leaf := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	intermediate := time.Date(2027, 6, 1, 0, 0, 0, 0, time.UTC)
+	root := time.Date(2035, 1, 1, 0, 0, 0, 0, time.UTC)
+	info := &models.TLSInfo{Chain: []models.CertificateInfo{{NotAfter: leaf}, {NotAfter: intermediate}, {NotAfter: root}}}
+
+	if got := chainExpiry(info); !got.Equal(intermediate) {
+		t.Errorf("chainExpiry() = %v, want the intermediate's %v", got, intermediate)
+	}
+}
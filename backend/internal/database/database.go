@@ -38,7 +38,7 @@ func InitDB() (*gorm.DB, error) {
 	}
 
 	// Auto migrate the schema
-	if err := db.AutoMigrate(&models.User{}, &models.URL{}, &models.Analysis{}); err != nil {
+	if err := db.AutoMigrate(&models.User{}, &models.URL{}, &models.Analysis{}, &models.CrawlJob{}, &models.CrawlPage{}, &models.DetectedTechnology{}, &models.CustomTechnology{}, &models.SitemapImport{}); err != nil {
 		return nil, fmt.Errorf("failed to migrate database: %w", err)
 	}
 
@@ -1,7 +1,10 @@
 package models
 
 import (
+	"math"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // User represents a user in the system
@@ -15,32 +18,335 @@ type User struct {
 
 // URL represents a URL to be analyzed
 type URL struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	URL          string    `json:"url" gorm:"not null;unique"`
+	Status       string    `json:"status" gorm:"default:'pending'"`  // pending, running, completed, failed
+	CrawlMode    string    `json:"crawl_mode" gorm:"default:'page'"` // page, site
+	MaxDepth     int       `json:"max_depth"`
+	MaxPages     int       `json:"max_pages"`
+	IgnoreRobots bool      `json:"ignore_robots"` // crawl even where robots.txt disallows it, for sites the user owns
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	UserID       uint      `json:"user_id" gorm:"not null"`
+	User         User      `json:"user"`
+	Analysis     Analysis  `json:"analysis,omitempty"`
+	CrawlJob     *CrawlJob `json:"crawl_job,omitempty" gorm:"constraint:OnDelete:CASCADE;"`
+}
+
+// Analysis represents the analysis results for a URL
+type Analysis struct {
+	ID                  uint                 `json:"id" gorm:"primaryKey"`
+	URLID               uint                 `json:"url_id" gorm:"not null;index;constraint:OnDelete:CASCADE;"`
+	FinalURL            string               `json:"final_url"`
+	RobotsDisallowed    bool                 `json:"robots_disallowed"`               // robots.txt disallows the URL for our user agent
+	Robots              string               `json:"robots" gorm:"type:json"`         // JSON string of the host's robots.txt rules
+	RedirectChain       string               `json:"redirect_chain" gorm:"type:json"` // JSON string of redirect hops
+	RedirectCount       int                  `json:"redirect_count"`
+	RedirectLoop        bool                 `json:"redirect_loop"`
+	LongRedirectChain   bool                 `json:"long_redirect_chain"`
+	StatusCode          int                  `json:"status_code"`
+	ContentType         string               `json:"content_type"`
+	ContentLength       int64                `json:"content_length"`            // declared Content-Length, -1 when unknown
+	BodySize            int64                `json:"body_size"`                 // bytes read after decompression
+	BodyTruncated       bool                 `json:"body_truncated"`            // body was longer than the crawler's limit
+	DocumentType        string               `json:"document_type"`             // html, pdf, image, text, other
+	Document            string               `json:"document" gorm:"type:json"` // JSON string of the PDF, image or text analysis
+	Compression         string               `json:"compression"`
+	Server              string               `json:"server"`
+	Timing              string               `json:"timing" gorm:"type:json"`      // JSON string of the response timing breakdown
+	TLSInfo             string               `json:"tls_info" gorm:"type:json"`    // JSON string of the TLS connection and certificate chain
+	CertExpiresAt       *time.Time           `json:"cert_expires_at" gorm:"index"` // earliest expiry in the certificate chain
+	CertDaysToExpiry    *int                 `json:"cert_days_to_expiry" gorm:"-"` // computed from CertExpiresAt when loaded
+	SecurityGrade       string               `json:"security_grade"`
+	SecurityHeaders     string               `json:"security_headers" gorm:"type:json"` // JSON string of the security header audit
+	HTMLVersion         string               `json:"html_version"`
+	HTMLVersionSource   string               `json:"html_version_source"` // doctype, heuristic
+	Doctype             string               `json:"doctype"`
+	DocumentMode        string               `json:"document_mode"`  // standards, limited_quirks, quirks
+	Charset             string               `json:"charset"`        // encoding the page was decoded with
+	CharsetSource       string               `json:"charset_source"` // bom, header, meta, sniffed
+	HeaderCharset       string               `json:"header_charset"` // charset declared in Content-Type
+	MetaCharset         string               `json:"meta_charset"`   // charset declared in a meta tag
+	CharsetMismatch     bool                 `json:"charset_mismatch"`
+	Title               string               `json:"title"`
+	Headings            string               `json:"headings" gorm:"type:json"`        // JSON string of heading counts
+	HeadingOutline      string               `json:"heading_outline" gorm:"type:json"` // JSON string of the heading tree
+	InternalLinks       int                  `json:"internal_links"`
+	ExternalLinks       int                  `json:"external_links"`
+	InaccessibleLinks   int                  `json:"inaccessible_links"`
+	UncheckedLinks      int                  `json:"unchecked_links"` // links skipped when the check budget ran out or the host kept rate limiting
+	HasLoginForm        bool                 `json:"has_login_form"`
+	LoginConfidence     float64              `json:"login_confidence"`                 // 0-1, of the most likely login form
+	LoginDetection      string               `json:"login_detection" gorm:"type:json"` // JSON string of the authentication forms found
+	PageWeight          int64                `json:"page_weight"`                      // bytes of the page and its assets
+	RequestCount        int                  `json:"request_count"`
+	Assets              string               `json:"assets" gorm:"type:json"` // JSON string of the asset inventory
+	MixedContentCount   int                  `json:"mixed_content_count"`
+	MixedContent        string               `json:"mixed_content" gorm:"type:json"`   // JSON string of http resources on an https page
+	InsecureForms       int                  `json:"insecure_forms"`                   // forms with high severity issues
+	Forms               string               `json:"forms" gorm:"type:json"`           // JSON string of the form inventory
+	SEO                 string               `json:"seo" gorm:"type:json"`             // JSON string of the SEO audit
+	StructuredData      string               `json:"structured_data" gorm:"type:json"` // JSON string of schema.org entities
+	AccessibilityIssues int                  `json:"accessibility_issues"`
+	Accessibility       string               `json:"accessibility" gorm:"type:json"`   // JSON string of the accessibility audit
+	BrokenLinks         string               `json:"broken_links" gorm:"type:json"`    // JSON string of broken links
+	ResourceCounts      string               `json:"resource_counts" gorm:"type:json"` // JSON string of reference counts by resource type
+	Technologies        []DetectedTechnology `json:"technologies" gorm:"constraint:OnDelete:CASCADE;"`
+	CreatedAt           time.Time            `json:"created_at"`
+	UpdatedAt           time.Time            `json:"updated_at"`
+}
+
+// BeforeSave fills JSON columns left empty by partial analyses, since empty strings are not valid JSON
+func (a *Analysis) BeforeSave(tx *gorm.DB) error {
+	for _, column := range []*string{
+		&a.Robots,
+		&a.RedirectChain,
+		&a.Document,
+		&a.Timing,
+		&a.TLSInfo,
+		&a.SecurityHeaders,
+		&a.Headings,
+		&a.HeadingOutline,
+		&a.LoginDetection,
+		&a.Assets,
+		&a.MixedContent,
+		&a.Forms,
+		&a.SEO,
+		&a.StructuredData,
+		&a.Accessibility,
+		&a.BrokenLinks,
+		&a.ResourceCounts,
+	} {
+		if *column == "" {
+			*column = "null"
+		}
+	}
+	return nil
+}
+
+// AfterFind computes the days left before the certificate expires
+func (a *Analysis) AfterFind(tx *gorm.DB) error {
+	a.CertDaysToExpiry = DaysUntil(a.CertExpiresAt)
+	return nil
+}
+
+// DaysUntil returns the whole days from now until t, negative once t has
+// passed, or nil when t is not set
+func DaysUntil(t *time.Time) *int {
+	if t == nil {
+		return nil
+	}
+	days := int(math.Floor(time.Until(*t).Hours() / 24))
+	return &days
+}
+
+// DetectedTechnology represents a technology fingerprinted on an analyzed page
+type DetectedTechnology struct {
+	ID         uint   `json:"id" gorm:"primaryKey"`
+	AnalysisID uint   `json:"analysis_id" gorm:"not null;index"`
+	Name       string `json:"name" gorm:"type:varchar(255);not null;index"`
+	Version    string `json:"version"`
+	Categories string `json:"categories" gorm:"type:json"` // JSON string of category names
+	Evidence   string `json:"evidence" gorm:"type:json"`   // JSON string of what matched
+}
+
+// BeforeSave fills JSON columns left empty, since empty strings are not valid JSON
+func (t *DetectedTechnology) BeforeSave(tx *gorm.DB) error {
+	for _, column := range []*string{&t.Categories, &t.Evidence} {
+		if *column == "" {
+			*column = "null"
+		}
+	}
+	return nil
+}
+
+// CustomTechnology represents a user-defined technology fingerprinting rule
+type CustomTechnology struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
-	URL       string    `json:"url" gorm:"not null;unique"`
-	Status    string    `json:"status" gorm:"default:'pending'"` // pending, running, completed, failed
+	UserID    uint      `json:"user_id" gorm:"not null;index"`
+	Name      string    `json:"name" gorm:"not null"`
+	Rule      string    `json:"rule" gorm:"type:json"` // JSON string of the rule, in the format of the built-in rule file
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
-	UserID    uint      `json:"user_id" gorm:"not null"`
-	User      User      `json:"user"`
-	Analysis  Analysis  `json:"analysis,omitempty"`
 }
 
-// Analysis represents the analysis results for a URL
-type Analysis struct {
+// CrawlJob represents a multi-page site crawl started from a URL
+type CrawlJob struct {
+	ID                     uint        `json:"id" gorm:"primaryKey"`
+	URLID                  uint        `json:"url_id" gorm:"not null;index"`
+	Status                 string      `json:"status" gorm:"default:'running'"` // running, completed, partial, failed
+	MaxDepth               int         `json:"max_depth"`
+	MaxPages               int         `json:"max_pages"`
+	PagesCrawled           int         `json:"pages_crawled"`
+	PagesFailed            int         `json:"pages_failed"`
+	TotalInternalLinks     int         `json:"total_internal_links"`
+	TotalExternalLinks     int         `json:"total_external_links"`
+	TotalInaccessibleLinks int         `json:"total_inaccessible_links"`
+	PagesWithLoginForm     int         `json:"pages_with_login_form"`
+	Error                  string      `json:"error,omitempty"`
+	StartedAt              time.Time   `json:"started_at"`
+	FinishedAt             *time.Time  `json:"finished_at,omitempty"`
+	Pages                  []CrawlPage `json:"pages,omitempty" gorm:"constraint:OnDelete:CASCADE;"`
+	CreatedAt              time.Time   `json:"created_at"`
+	UpdatedAt              time.Time   `json:"updated_at"`
+}
+
+// CrawlPage represents a single page discovered during a site crawl
+type CrawlPage struct {
 	ID                uint      `json:"id" gorm:"primaryKey"`
-	URLID             uint      `json:"url_id" gorm:"not null;index;constraint:OnDelete:CASCADE;"`
-	HTMLVersion       string    `json:"html_version"`
+	CrawlJobID        uint      `json:"crawl_job_id" gorm:"not null;index"`
+	URL               string    `json:"url" gorm:"type:varchar(2048);not null"`
+	Depth             int       `json:"depth"`
+	Status            string    `json:"status"` // completed, failed
+	Error             string    `json:"error,omitempty"`
 	Title             string    `json:"title"`
-	Headings          string    `json:"headings" gorm:"type:json"` // JSON string of heading counts
 	InternalLinks     int       `json:"internal_links"`
 	ExternalLinks     int       `json:"external_links"`
 	InaccessibleLinks int       `json:"inaccessible_links"`
-	HasLoginForm      bool      `json:"has_login_form"`
-	BrokenLinks       string    `json:"broken_links" gorm:"type:json"` // JSON string of broken links
+	Analysis          string    `json:"analysis,omitempty" gorm:"type:json"` // JSON string of the page analysis
 	CreatedAt         time.Time `json:"created_at"`
 	UpdatedAt         time.Time `json:"updated_at"`
 }
 
+// BeforeSave stores failed pages with a null analysis, since empty strings are not valid JSON
+func (p *CrawlPage) BeforeSave(tx *gorm.DB) error {
+	if p.Analysis == "" {
+		p.Analysis = "null"
+	}
+	return nil
+}
+
+// RedirectHop represents a single redirect response on the way to a page
+type RedirectHop struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code"`
+	Location   string `json:"location"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// ResponseTiming represents the phases of fetching a page, in milliseconds
+type ResponseTiming struct {
+	DNSMs          float64 `json:"dns_ms"`
+	ConnectMs      float64 `json:"connect_ms"`
+	TLSHandshakeMs float64 `json:"tls_handshake_ms"`
+	TTFBMs         float64 `json:"ttfb_ms"`
+	DownloadMs     float64 `json:"download_ms"`
+	TotalMs        float64 `json:"total_ms"`
+	ReusedConn     bool    `json:"reused_conn"` // DNS and connect are zero on a reused connection
+}
+
+// TLSInfo represents the negotiated TLS connection of an https page
+type TLSInfo struct {
+	Version       string            `json:"version"`
+	CipherSuite   string            `json:"cipher_suite"`
+	HostnameMatch bool              `json:"hostname_match"`
+	Verified      bool              `json:"verified"` // the chain leads to a trusted root and covers the host
+	VerifyError   string            `json:"verify_error,omitempty"`
+	Chain         []CertificateInfo `json:"chain"` // leaf first
+}
+
+// CertificateInfo represents one certificate of a peer chain
+type CertificateInfo struct {
+	Subject      string    `json:"subject"`
+	Issuer       string    `json:"issuer"`
+	SANs         []string  `json:"sans"`
+	SerialNumber string    `json:"serial_number"`
+	NotBefore    time.Time `json:"not_before"`
+	NotAfter     time.Time `json:"not_after"`
+	KeyType      string    `json:"key_type"`
+	KeySize      int       `json:"key_size"`
+	IsCA         bool      `json:"is_ca"`
+}
+
+// SecurityHeaderAudit represents the graded security headers of a response
+type SecurityHeaderAudit struct {
+	Grade    string              `json:"grade"` // A-F
+	Score    int                 `json:"score"` // 0-100
+	Findings []SecurityFinding   `json:"findings"`
+	CSP      map[string][]string `json:"csp,omitempty"` // parsed CSP directives
+	HSTS     *HSTSPolicy         `json:"hsts,omitempty"`
+}
+
+// SecurityFinding represents the result of checking one security header
+type SecurityFinding struct {
+	Header   string `json:"header"`
+	Value    string `json:"value,omitempty"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// HSTSPolicy represents a parsed Strict-Transport-Security header
+type HSTSPolicy struct {
+	MaxAge            int64 `json:"max_age"`
+	IncludeSubDomains bool  `json:"include_subdomains"`
+	Preload           bool  `json:"preload"`
+	PreloadEligible   bool  `json:"preload_eligible"`
+}
+
+// Finding severities
+const (
+	SeverityPass   = "pass"
+	SeverityInfo   = "info"
+	SeverityLow    = "low"
+	SeverityMedium = "medium"
+	SeverityHigh   = "high"
+)
+
+// HTML version sources
+const (
+	VersionSourceDoctype   = "doctype"
+	VersionSourceHeuristic = "heuristic"
+)
+
+// Document rendering modes
+const (
+	DocumentModeStandards     = "standards"
+	DocumentModeLimitedQuirks = "limited_quirks"
+	DocumentModeQuirks        = "quirks"
+)
+
+// Where a sitemap was found
+const (
+	SitemapSourceRobots  = "robots"
+	SitemapSourceDefault = "default"
+	SitemapSourceIndex   = "index"
+	SitemapSourceGiven   = "given"
+)
+
+// Sitemap problem codes
+const (
+	SitemapErrorFetch        = "fetch_failed"
+	SitemapErrorInvalidXML   = "invalid_xml"
+	SitemapErrorTooLarge     = "too_large"
+	SitemapErrorInvalidURL   = "invalid_url"
+	SitemapErrorCrossHost    = "cross_host"
+	SitemapErrorLastMod      = "invalid_lastmod"
+	SitemapErrorStatus       = "non_200"
+	SitemapErrorNonCanonical = "non_canonical"
+	SitemapErrorDisallowed   = "robots_disallowed"
+	SitemapErrorUnreachable  = "unreachable"
+	// Problems adding a listed URL during an import
+	SitemapErrorExists       = "already_exists"
+	SitemapErrorImportFailed = "import_failed"
+)
+
+// Kinds of document a response can hold
+const (
+	DocumentHTML  = "html"
+	DocumentPDF   = "pdf"
+	DocumentImage = "image"
+	DocumentText  = "text"
+	DocumentOther = "other"
+)
+
+// Sources a page's character encoding was taken from
+const (
+	CharsetSourceBOM     = "bom"
+	CharsetSourceHeader  = "header"
+	CharsetSourceMeta    = "meta"
+	CharsetSourceSniffed = "sniffed"
+)
+
 // HeadingCount represents the count of headings by level
 type HeadingCount struct {
 	H1 int `json:"h1"`
@@ -51,11 +357,405 @@ type HeadingCount struct {
 	H6 int `json:"h6"`
 }
 
+// HeadingOutline represents the heading tree of a page and its structural problems
+type HeadingOutline struct {
+	Headings      []*OutlineHeading `json:"headings"`
+	Total         int               `json:"total"`
+	H1Count       int               `json:"h1_count"`
+	MissingH1     bool              `json:"missing_h1"`
+	MultipleH1    bool              `json:"multiple_h1"`
+	SkippedLevels int               `json:"skipped_levels"`
+	EmptyHeadings int               `json:"empty_headings"`
+}
+
+// OutlineHeading represents a heading and the headings nested beneath it
+type OutlineHeading struct {
+	Order        int               `json:"order"`
+	Level        int               `json:"level"`
+	Text         string            `json:"text"`
+	Empty        bool              `json:"empty,omitempty"`
+	SkippedLevel bool              `json:"skipped_level,omitempty"` // more than one level below the previous heading
+	Children     []*OutlineHeading `json:"children,omitempty"`
+}
+
+// SEOAudit represents a page's search and social metadata and the problems found with it
+type SEOAudit struct {
+	Title             string              `json:"title"`
+	TitleLength       int                 `json:"title_length"`
+	MetaDescription   string              `json:"meta_description"`
+	DescriptionLength int                 `json:"description_length"`
+	Canonical         string              `json:"canonical"`
+	CanonicalIsSelf   bool                `json:"canonical_is_self"`
+	MetaRobots        string              `json:"meta_robots"`
+	XRobotsTag        string              `json:"x_robots_tag"`
+	Indexable         bool                `json:"indexable"`
+	Hreflang          []HreflangAlternate `json:"hreflang"`
+	OpenGraph         map[string]string   `json:"open_graph"`
+	TwitterCard       map[string]string   `json:"twitter_card"`
+	Viewport          string              `json:"viewport"`
+	Issues            []SEOIssue          `json:"issues"`
+}
+
+// HreflangAlternate represents a language alternate of a page
+type HreflangAlternate struct {
+	Lang string `json:"lang"`
+	URL  string `json:"url"`
+}
+
+// SEOIssue represents a single SEO problem
+type SEOIssue struct {
+	Code     string `json:"code"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// StructuredData represents the schema.org entities embedded in a page
+type StructuredData struct {
+	Entities        []StructuredEntity    `json:"entities"`
+	Errors          []StructuredDataError `json:"errors"`
+	InvalidEntities int                   `json:"invalid_entities"`
+}
+
+// StructuredEntity represents a normalized schema.org entity
+type StructuredEntity struct {
+	Format            string                 `json:"format"` // json-ld, microdata, rdfa
+	Types             []string               `json:"types"`
+	Properties        map[string]interface{} `json:"properties"`
+	MissingProperties []string               `json:"missing_properties,omitempty"`
+}
+
+// StructuredDataError represents a structured data block that could not be parsed
+type StructuredDataError struct {
+	Format  string `json:"format"`
+	Block   int    `json:"block"` // position of the block among the page's scripts
+	Message string `json:"message"`
+}
+
+// Structured data formats
+const (
+	FormatJSONLD    = "json-ld"
+	FormatMicrodata = "microdata"
+	FormatRDFa      = "rdfa"
+)
+
+// LoginDetection represents the authentication forms and single sign-on
+// options found on a page
+type LoginDetection struct {
+	Confidence   float64    `json:"confidence"` // of the most likely login form
+	Methods      []string   `json:"methods"`    // password, otp, sso
+	SSOProviders []string   `json:"sso_providers"`
+	Forms        []AuthForm `json:"forms"`
+}
+
+// AuthForm represents a form classified by the authentication step it performs
+type AuthForm struct {
+	Kind       string   `json:"kind"` // login, registration, password_reset, password_change
+	Confidence float64  `json:"confidence"`
+	Methods    []string `json:"methods"`
+	Element    string   `json:"element"` // CSS path of the form
+	Action     string   `json:"action,omitempty"`
+	Hidden     bool     `json:"hidden"`
+	Evidence   []string `json:"evidence"`
+}
+
+// Authentication form kinds
+const (
+	AuthFormLogin          = "login"
+	AuthFormRegistration   = "registration"
+	AuthFormPasswordReset  = "password_reset"
+	AuthFormPasswordChange = "password_change"
+)
+
+// Authentication methods
+const (
+	AuthMethodPassword = "password"
+	AuthMethodOTP      = "otp"
+	AuthMethodSSO      = "sso"
+)
+
+// FormInfo represents a form on a page and the security problems found with it
+type FormInfo struct {
+	Element      string      `json:"element"` // CSS path of the form
+	Method       string      `json:"method"`  // GET, POST, DIALOG
+	Action       string      `json:"action"`  // resolved against the page URL
+	CrossOrigin  bool        `json:"cross_origin"`
+	Fields       []FormField `json:"fields"`
+	HasPassword  bool        `json:"has_password"`
+	CSRFToken    string      `json:"csrf_token,omitempty"` // name of the anti-CSRF token field, if any
+	Autocomplete string      `json:"autocomplete"`         // the form's autocomplete attribute, on by default
+	Issues       []FormIssue `json:"issues"`
+}
+
+// FormField represents a named control of a form
+type FormField struct {
+	Name         string `json:"name"`
+	Type         string `json:"type"`
+	Autocomplete string `json:"autocomplete,omitempty"`
+	Required     bool   `json:"required,omitempty"`
+}
+
+// FormIssue represents a single security problem with a form
+type FormIssue struct {
+	Code     string `json:"code"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// AccessibilityAudit represents the accessibility problems found on a page
+type AccessibilityAudit struct {
+	Findings []AccessibilityFinding `json:"findings"`
+	Total    int                    `json:"total"`
+	ByRule   map[string]int         `json:"by_rule"`
+}
+
+// AccessibilityFinding represents a single accessibility problem and the element it was found on
+type AccessibilityFinding struct {
+	Rule      string `json:"rule"`
+	Criterion string `json:"criterion"`      // WCAG success criterion, e.g. 1.1.1
+	Name      string `json:"criterion_name"` // e.g. Non-text Content
+	Level     string `json:"level"`          // WCAG conformance level: A, AA
+	Severity  string `json:"severity"`
+	Message   string `json:"message"`
+	Element   string `json:"element"` // CSS path of the element
+}
+
+// RobotsInfo represents a host's robots.txt and how it applies to a page
+type RobotsInfo struct {
+	URL         string        `json:"url"`
+	StatusCode  int           `json:"status_code"` // 0 when robots.txt could not be fetched
+	Error       string        `json:"error,omitempty"`
+	Allowed     bool          `json:"allowed"`
+	MatchedRule string        `json:"matched_rule,omitempty"` // e.g. "Disallow: /private/"
+	Ignored     bool          `json:"ignored"`                // the URL is set to ignore robots.txt
+	CrawlDelay  float64       `json:"crawl_delay,omitempty"`  // seconds, for our user agent
+	Groups      []RobotsGroup `json:"groups"`
+	Sitemaps    []string      `json:"sitemaps"`
+}
+
+// RobotsGroup represents the rules robots.txt gives a set of user agents
+type RobotsGroup struct {
+	UserAgents []string     `json:"user_agents"`
+	Rules      []RobotsRule `json:"rules"`
+	CrawlDelay float64      `json:"crawl_delay,omitempty"`
+}
+
+// RobotsRule represents a single Allow or Disallow line
+type RobotsRule struct {
+	Allow bool   `json:"allow"`
+	Path  string `json:"path"`
+}
+
+// SitemapImport represents a sitemap import, which reads and checks a site's
+// sitemaps and adds the URLs they list in the background
+type SitemapImport struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"user_id" gorm:"not null;index"`
+	URL        string     `json:"url" gorm:"type:varchar(2048);not null"`
+	Status     string     `json:"status" gorm:"default:'running'"` // running, completed, failed
+	DryRun     bool       `json:"dry_run"`
+	Imported   int        `json:"imported"`
+	Existing   int        `json:"existing"`                // already in the user's list
+	Failed     int        `json:"failed"`                  // not added, with the reason in the report's errors
+	Report     string     `json:"report" gorm:"type:json"` // JSON string of the SitemapReport
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// BeforeSave stores running imports with a null report, since empty strings are not valid JSON
+func (i *SitemapImport) BeforeSave(tx *gorm.DB) error {
+	if i.Report == "" {
+		i.Report = "null"
+	}
+	return nil
+}
+
+// SitemapReport represents the sitemaps of a site, the URLs they list and the
+// problems found in them
+type SitemapReport struct {
+	Sitemaps  []SitemapFile  `json:"sitemaps"`
+	URLs      []SitemapURL   `json:"urls"`
+	Errors    []SitemapError `json:"errors"`
+	Filtered  int            `json:"filtered"`  // URLs left out by the include and exclude patterns
+	Checked   int            `json:"checked"`   // URLs requested to verify their status and canonical link
+	Unchecked int            `json:"unchecked"` // URLs left out of the check by its limit
+}
+
+// SitemapFile represents a sitemap or sitemap index that was read
+type SitemapFile struct {
+	URL     string `json:"url"`
+	Source  string `json:"source"` // robots, default, index, given
+	Index   bool   `json:"index"`
+	Gzipped bool   `json:"gzipped"`
+	Entries int    `json:"entries"` // URLs or child sitemaps listed
+	Error   string `json:"error,omitempty"`
+}
+
+// SitemapURL represents a single <url> entry of a sitemap
+type SitemapURL struct {
+	Loc        string `json:"loc"`
+	LastMod    string `json:"lastmod,omitempty"`
+	ChangeFreq string `json:"changefreq,omitempty"`
+	Priority   string `json:"priority,omitempty"`
+	Sitemap    string `json:"sitemap"`
+}
+
+// SitemapError represents a problem with a sitemap or one of its entries
+type SitemapError struct {
+	Sitemap string `json:"sitemap"`
+	URL     string `json:"url,omitempty"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// DocumentInfo represents the analysis of a response that is not HTML
+type DocumentInfo struct {
+	Type  string     `json:"type"` // pdf, image, text
+	Size  int64      `json:"size"` // bytes read, up to the crawler's limit
+	PDF   *PDFInfo   `json:"pdf,omitempty"`
+	Image *ImageInfo `json:"image,omitempty"`
+	Text  *TextInfo  `json:"text,omitempty"`
+}
+
+// PDFInfo represents the metadata read from a PDF
+type PDFInfo struct {
+	Version   string   `json:"version"`
+	Pages     int      `json:"pages"`
+	Title     string   `json:"title,omitempty"`
+	Author    string   `json:"author,omitempty"`
+	Links     []string `json:"links"`     // URIs of link annotations
+	Encrypted bool     `json:"encrypted"` // strings and streams cannot be read
+}
+
+// ImageInfo represents the format and dimensions of an image
+type ImageInfo struct {
+	Format string `json:"format"` // png, jpeg, gif, webp, svg
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// TextInfo represents the size of a plain text, JSON or XML document
+type TextInfo struct {
+	Length  int    `json:"length"` // characters
+	Lines   int    `json:"lines"`
+	Charset string `json:"charset"`
+}
+
+// AssetInventory represents the assets a page loads and their combined weight
+type AssetInventory struct {
+	Assets          []Asset                     `json:"assets"`
+	HTMLBytes       int64                       `json:"html_bytes"`
+	TotalBytes      int64                       `json:"total_bytes"` // page weight, HTML included
+	ThirdPartyBytes int64                       `json:"third_party_bytes"`
+	Requests        int                         `json:"requests"`   // HTML included
+	Unmeasured      int                         `json:"unmeasured"` // assets whose size could not be found
+	ByType          map[string]AssetTypeSummary `json:"by_type"`
+}
+
+// Asset represents a script, stylesheet, image, font or media file used by a page
+type Asset struct {
+	URL         string `json:"url"`
+	Type        string `json:"type"` // one of the Resource* types
+	ContentType string `json:"content_type,omitempty"`
+	Size        int64  `json:"size"`        // bytes, -1 when unknown
+	SizeSource  string `json:"size_source"` // content_length, fetched
+	ThirdParty  bool   `json:"third_party"`
+}
+
+// AssetTypeSummary represents the assets of one type on a page
+type AssetTypeSummary struct {
+	Count int   `json:"count"`
+	Bytes int64 `json:"bytes"`
+}
+
+// Asset size sources
+const (
+	SizeFromContentLength = "content_length"
+	SizeFromFetch         = "fetched"
+)
+
+// MixedContent represents the http resources and form actions of an https page
+type MixedContent struct {
+	Active   int                `json:"active"`
+	Passive  int                `json:"passive"`
+	Forms    int                `json:"forms"`
+	Upgraded bool               `json:"upgraded"` // upgrade-insecure-requests makes browsers fetch them over https
+	Items    []MixedContentItem `json:"items"`
+}
+
+// MixedContentItem represents a single http reference on an https page
+type MixedContentItem struct {
+	URL          string       `json:"url"`
+	ResourceType string       `json:"resource_type"`
+	Class        string       `json:"class"` // active, passive, form
+	Location     LinkLocation `json:"location"`
+}
+
+// Mixed content classes
+const (
+	MixedContentActive  = "active"  // blocked by browsers
+	MixedContentPassive = "passive" // loaded with a warning
+	MixedContentForm    = "form"    // submitted without encryption
+)
+
 // BrokenLink represents a broken link with its status code
 type BrokenLink struct {
-	URL        string `json:"url"`
-	StatusCode int    `json:"status_code"`
-	Error      string `json:"error,omitempty"`
+	URL          string         `json:"url"`
+	ResourceType string         `json:"resource_type"`
+	StatusCode   int            `json:"status_code"`
+	Error        string         `json:"error,omitempty"`
+	Category     string         `json:"category"`
+	Attempts     int            `json:"attempts"`
+	CheckedAt    time.Time      `json:"checked_at"`
+	Locations    []LinkLocation `json:"locations,omitempty"`
+}
+
+// LinkLocation describes where a link appears in the page
+type LinkLocation struct {
+	AnchorText string `json:"anchor_text"`
+	CSSPath    string `json:"css_path"`
+	Rel        string `json:"rel,omitempty"`
+	Target     string `json:"target,omitempty"`
+	Line       int    `json:"line,omitempty"` // approximate source line
+}
+
+// Resource types of references found in a page
+const (
+	ResourceLink       = "link"
+	ResourceImage      = "image"
+	ResourceScript     = "script"
+	ResourceStylesheet = "stylesheet"
+	ResourceIframe     = "iframe"
+	ResourceMedia      = "media"
+	ResourceFont       = "font"
+)
+
+// ResourceCount represents how many references of one resource type a page has
+type ResourceCount struct {
+	Total  int `json:"total"`
+	Broken int `json:"broken"`
+}
+
+// Broken link failure categories
+const (
+	FailureDNS              = "dns_failure"
+	FailureConnRefused      = "connection_refused"
+	FailureTimeout          = "timeout"
+	FailureTLS              = "tls_error"
+	FailureRedirectLoop     = "redirect_loop"
+	FailureTooManyRedirects = "too_many_redirects"
+	FailureClientError      = "client_error" // 4xx
+	FailureServerError      = "server_error" // 5xx
+	FailureOther            = "other"
+)
+
+// BrokenLinkListResponse represents a filtered list of broken links with per-category counts
+type BrokenLinkListResponse struct {
+	BrokenLinks []BrokenLink   `json:"broken_links"`
+	Total       int            `json:"total"`
+	Counts      map[string]int `json:"counts"`
 }
 
 // LoginRequest represents a login request
@@ -72,7 +772,27 @@ type RegisterRequest struct {
 
 // AddURLRequest represents a request to add a new URL
 type AddURLRequest struct {
-	URL string `json:"url" binding:"required,url"`
+	URL          string `json:"url" binding:"required,url"`
+	CrawlMode    string `json:"crawl_mode" binding:"omitempty,oneof=page site"`
+	MaxDepth     *int   `json:"max_depth" binding:"omitempty,min=0,max=10"` // nil uses the default, 0 crawls only the start page
+	MaxPages     int    `json:"max_pages" binding:"omitempty,min=1,max=1000"`
+	IgnoreRobots bool   `json:"ignore_robots"`
+}
+
+// UpdateURLRequest represents a request to change a URL's crawl settings
+type UpdateURLRequest struct {
+	IgnoreRobots *bool `json:"ignore_robots"`
+}
+
+// SitemapImportRequest represents a request to import the URLs listed in a
+// site's sitemaps. URL is either the site, whose sitemaps are discovered, or
+// a sitemap itself.
+type SitemapImportRequest struct {
+	URL     string   `json:"url" binding:"required,url"`
+	Include []string `json:"include"` // regular expressions; a URL must match one when set
+	Exclude []string `json:"exclude"` // regular expressions; a URL matching any is skipped
+	DryRun  bool     `json:"dry_run"` // report without importing
+	Crawl   bool     `json:"crawl"`   // start analyzing imported URLs right away
 }
 
 // URLListResponse represents a paginated list of URLs
@@ -85,6 +805,15 @@ type URLListResponse struct {
 	Stats      Stats `json:"stats"`
 }
 
+// CrawlPageListResponse represents a paginated list of crawled pages
+type CrawlPageListResponse struct {
+	Pages      []CrawlPage `json:"pages"`
+	Total      int64       `json:"total"`
+	Page       int         `json:"page"`
+	PageSize   int         `json:"page_size"`
+	TotalPages int         `json:"total_pages"`
+}
+
 // Stats represents URL status statistics
 type Stats struct {
 	Pending   int64 `json:"pending"`
@@ -110,3 +839,12 @@ type CrawlStatus struct {
 	Status string `json:"status"`
 	Error  string `json:"error,omitempty"`
 }
+
+// SitemapImportStatus represents a sitemap import update sent over WebSocket
+type SitemapImportStatus struct {
+	ImportID uint   `json:"import_id"`
+	Status   string `json:"status"`
+	Imported int    `json:"imported"`
+	Existing int    `json:"existing"`
+	Failed   int    `json:"failed"`
+}
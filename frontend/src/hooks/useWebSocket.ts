@@ -22,9 +22,11 @@ export const useWebSocket = (options: UseWebSocketOptions = {}) => {
   const connect = useCallback(() => {
     const wsUrl = import.meta.env.VITE_WS_URL || 
       (import.meta.env.VITE_API_URL || 'http://localhost:8080').replace('http', 'ws') + '/api/ws'
+    // Browsers cannot set headers on a WebSocket, so the token goes in the query
+    const token = localStorage.getItem('token')
     
     try {
-      const ws = new WebSocket(wsUrl)
+      const ws = new WebSocket(token ? `${wsUrl}?token=${encodeURIComponent(token)}` : wsUrl)
       wsRef.current = ws
 
       ws.onopen = () => {